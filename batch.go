@@ -0,0 +1,108 @@
+package ttsig
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// defaultSchedule holds the key schedules of the built-in KeySet, shared by
+// every signature that does not override Keys.
+var defaultSchedule = sync.OnceValues(func() (*signer.Schedule, error) {
+	return signer.NewSchedule(nil)
+})
+
+// scheduleCache shares key schedules between signatures that use the same
+// *KeySet. A nil cache builds a new schedule for every custom KeySet.
+type scheduleCache struct {
+	mu sync.Mutex
+	m  map[*KeySet]*signer.Schedule
+}
+
+func (c *scheduleCache) get(keys *KeySet) (*signer.Schedule, error) {
+	if keys == nil {
+		return defaultSchedule()
+	}
+	if c == nil {
+		return signer.NewSchedule(keys)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.m[keys]; ok {
+		return s, nil
+	}
+	s, err := signer.NewSchedule(keys)
+	if err != nil {
+		return nil, err
+	}
+	if c.m == nil {
+		c.m = make(map[*KeySet]*signer.Schedule)
+	}
+	c.m[keys] = s
+	return s, nil
+}
+
+// BatchOptions configures SignBatch.
+type BatchOptions struct {
+	// Workers is the number of requests signed concurrently. Zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+}
+
+// SignBatch signs every config with a bounded pool of workers. Key
+// schedules are computed once per distinct *KeySet and shared, so configs
+// should point at the same KeySet rather than at copies of it.
+//
+// The results are index-aligned with cfgs: headers[i] is set when errs[i]
+// is nil. When ctx is cancelled, configs not yet signed fail with
+// ctx.Err(). A config must not share its Body, Rand or Query with another
+// config in the batch.
+func SignBatch(ctx context.Context, cfgs []SignConfig, opts BatchOptions) ([]SignedHeaders, []error) {
+	headers := make([]SignedHeaders, len(cfgs))
+	errs := make([]error, len(cfgs))
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(cfgs) {
+		workers = len(cfgs)
+	}
+
+	schedules := &scheduleCache{}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				headers[i], errs[i] = signRequest(cfgs[i], schedules, nil)
+			}
+		}()
+	}
+
+	i := 0
+feed:
+	for ; i < len(cfgs); i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for ; i < len(cfgs); i++ {
+		errs[i] = ctx.Err()
+	}
+	return headers, errs
+}
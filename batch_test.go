@@ -0,0 +1,86 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// batchConfigs returns n distinct requests. When seeded, each gets its own
+// fixed random bytes so the output is deterministic.
+func batchConfigs(n int, seeded bool) []SignConfig {
+	cfgs := make([]SignConfig, n)
+	for i := range cfgs {
+		cfgs[i] = SignConfig{
+			RawRequestParameters: fmt.Sprintf("device_id=7300000000000000001&aid=1233&cursor=%d", i),
+			RequestPayload:       fmt.Sprintf("user_id=%d&type=1", i),
+			Cookie:               "sessionid=5f1c3a",
+			Timestamp:            time.UnixMilli(1700000000123 + int64(i)),
+		}
+		if seeded {
+			cfgs[i].Rand = bytes.NewReader([]byte{byte(i), 2, 3, 4, 5, 6, 7, 8})
+		}
+	}
+	return cfgs
+}
+
+func TestSignBatch(t *testing.T) {
+	headers, errs := SignBatch(context.Background(), batchConfigs(32, true), BatchOptions{Workers: 4})
+	for i, want := range batchConfigs(32, true) {
+		if errs[i] != nil {
+			t.Fatalf("config %d: %v", i, errs[i])
+		}
+		w, err := SignRequest(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, v := range w {
+			if headers[i][name] != v {
+				t.Errorf("config %d %s = %s, SignRequest gives %s", i, name, headers[i][name], v)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, errs = SignBatch(ctx, batchConfigs(4, false), BatchOptions{})
+	for i, err := range errs {
+		if err != context.Canceled {
+			t.Errorf("config %d after cancel: %v", i, err)
+		}
+	}
+}
+
+const benchBatch = 256
+
+func BenchmarkSignBatch(b *testing.B) {
+	cfgs := batchConfigs(benchBatch, false)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		_, errs := SignBatch(ctx, cfgs, BatchOptions{})
+		for _, err := range errs {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchBatch), "ns/sig")
+}
+
+// BenchmarkSignRequestLoop signs the same batch one SignRequest at a time,
+// the baseline SignBatch is measured against.
+func BenchmarkSignRequestLoop(b *testing.B) {
+	cfgs := batchConfigs(benchBatch, false)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, cfg := range cfgs {
+			if _, err := SignRequest(cfg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchBatch), "ns/sig")
+}
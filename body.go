@@ -0,0 +1,128 @@
+package ttsig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Body is a request body to be signed. It is read exactly once; the bytes
+// that go on the wire are kept so they can be sent after signing, and every
+// digest the signers need is computed in that same pass.
+//
+// x-ss-stub, the Gorgon body hash and the Argus body hash all cover the
+// bytes on the wire, so a compressed body must be hashed after compression.
+// NewGzipBody does that for uncompressed input; already compressed bytes
+// go to NewBody and keep their Content-Encoding with WithEncoding.
+type Body struct {
+	src         io.Reader
+	gzip        bool
+	encoding    string
+	contentType string
+
+	once   sync.Once
+	wire   []byte
+	digest BodyDigest
+	err    error
+}
+
+// BodyDigest holds the digests of a body's wire bytes.
+type BodyDigest struct {
+	MD5    [16]byte
+	Length int64
+}
+
+// Stub returns the x-ss-stub value, or "" for an empty body.
+func (d BodyDigest) Stub() string {
+	if d.Length == 0 {
+		return ""
+	}
+	return strings.ToUpper(hex.EncodeToString(d.MD5[:]))
+}
+
+// md5Hex returns the lower-case hex MD5 that Gorgon mixes into its base string.
+func (d BodyDigest) md5Hex() string {
+	return hex.EncodeToString(d.MD5[:])
+}
+
+// NewBody returns a Body for bytes that are sent as-is. Bytes that are
+// already compressed keep their Content-Encoding with WithEncoding.
+func NewBody(data []byte) *Body {
+	return &Body{src: bytes.NewReader(data)}
+}
+
+// NewBodyReader returns a Body that streams from r.
+func NewBodyReader(r io.Reader) *Body {
+	return &Body{src: r}
+}
+
+// NewGzipBody returns a Body that gzip-compresses r while reading it. The
+// digests cover the compressed bytes and ContentEncoding reports "gzip".
+func NewGzipBody(r io.Reader) *Body {
+	return &Body{src: r, gzip: true, encoding: "gzip"}
+}
+
+func (b *Body) load() {
+	b.once.Do(func() {
+		var wire bytes.Buffer
+		h := md5.New()
+		sink := io.MultiWriter(&wire, h)
+
+		if b.gzip {
+			zw := gzip.NewWriter(sink)
+			if _, b.err = io.Copy(zw, b.src); b.err != nil {
+				return
+			}
+			if b.err = zw.Close(); b.err != nil {
+				return
+			}
+		} else if _, b.err = io.Copy(sink, b.src); b.err != nil {
+			return
+		}
+
+		b.wire = wire.Bytes()
+		copy(b.digest.MD5[:], h.Sum(nil))
+		b.digest.Length = int64(len(b.wire))
+	})
+}
+
+// Digest reads the body if needed and returns its digests.
+func (b *Body) Digest() (BodyDigest, error) {
+	b.load()
+	return b.digest, b.err
+}
+
+// Bytes returns the wire bytes.
+func (b *Body) Bytes() ([]byte, error) {
+	b.load()
+	return b.wire, b.err
+}
+
+// Reader returns a fresh reader over the wire bytes, suitable for
+// http.Request.Body.
+func (b *Body) Reader() (io.Reader, error) {
+	b.load()
+	return bytes.NewReader(b.wire), b.err
+}
+
+// WithEncoding records the Content-Encoding the wire bytes already carry
+// and returns b. It does not compress; use NewGzipBody for that.
+func (b *Body) WithEncoding(encoding string) *Body {
+	b.encoding = encoding
+	return b
+}
+
+// ContentEncoding returns the Content-Encoding of the wire bytes, or "".
+func (b *Body) ContentEncoding() string {
+	return b.encoding
+}
+
+// ContentType returns the Content-Type set by the form and multipart
+// builders, or "" when the caller sets it.
+func (b *Body) ContentType() string {
+	return b.contentType
+}
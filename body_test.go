@@ -0,0 +1,94 @@
+package ttsig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBodyDigest(t *testing.T) {
+	data := []byte(strings.Repeat("user_id=1&type=1&", 1000))
+	want := md5.Sum(data)
+
+	for _, tt := range []struct {
+		name string
+		body *Body
+	}{
+		{"bytes", NewBody(data)},
+		{"reader", NewBodyReader(bytes.NewReader(data))},
+		{"one byte reads", NewBodyReader(iotest.OneByteReader(bytes.NewReader(data)))},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.body.Digest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.MD5 != want || d.Length != int64(len(data)) {
+				t.Errorf("digest %x/%d, want %x/%d", d.MD5, d.Length, want, len(data))
+			}
+			wire, _ := tt.body.Bytes()
+			if !bytes.Equal(wire, data) {
+				t.Error("wire bytes differ from the input")
+			}
+			if enc := tt.body.ContentEncoding(); enc != "" {
+				t.Errorf("ContentEncoding %q", enc)
+			}
+		})
+	}
+}
+
+func TestGzipBodyDigest(t *testing.T) {
+	data := []byte(strings.Repeat(`{"event":"launch"}`, 500))
+	body := NewGzipBody(bytes.NewReader(data))
+	wire, err := body.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.ContentEncoding() != "gzip" {
+		t.Errorf("ContentEncoding %q, want gzip", body.ContentEncoding())
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(wire))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(plain, data) {
+		t.Fatalf("wire bytes do not decompress to the input: %v", err)
+	}
+
+	// The digests cover the compressed bytes, exactly as if they had been
+	// compressed beforehand and passed to NewBody.
+	got, _ := body.Digest()
+	want, _ := NewBody(wire).WithEncoding("gzip").Digest()
+	if got != want {
+		t.Errorf("gzip digest %x/%d, NewBody of the same bytes %x/%d", got.MD5, got.Length, want.MD5, want.Length)
+	}
+	if got.Stub() != strings.ToUpper(want.md5Hex()) {
+		t.Errorf("stub %s", got.Stub())
+	}
+}
+
+func TestBodyEmpty(t *testing.T) {
+	d, err := NewBody(nil).Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Length != 0 || d.Stub() != "" {
+		t.Errorf("empty body digest %+v, stub %q", d, d.Stub())
+	}
+}
+
+func TestBodyReaderError(t *testing.T) {
+	b := NewBodyReader(iotest.ErrReader(io.ErrUnexpectedEOF))
+	if _, err := b.Digest(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Digest error %v", err)
+	}
+	if _, err := b.Bytes(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Bytes error %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+// Command libttsig builds the signer as a C shared library:
+//
+//	go build -buildmode=c-shared -o libttsig.so ./cmd/libttsig
+//
+// which also writes libttsig.h. Every function takes and returns
+// NUL-terminated UTF-8 strings and returns 0 on success or -1 on failure.
+// On success *out_json holds the result; on failure it holds
+// {"error": "..."}. Either way it must be released with ttsig_free.
+//
+// testdata/sign.c is a small C program linking the library.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"unsafe"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/signer"
+)
+
+func main() {}
+
+// ttsig_sign signs the JSON-encoded SignConfig in json_config and writes
+// the signed headers as a JSON object.
+//
+//export ttsig_sign
+func ttsig_sign(jsonConfig *C.char, outJSON **C.char) C.int {
+	return respond(outJSON, func() (any, error) {
+		var cfg ttsig.SignConfig
+		if err := json.Unmarshal([]byte(C.GoString(jsonConfig)), &cfg); err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+		return ttsig.SignRequest(cfg)
+	})
+}
+
+// ttsig_decode_argus decrypts an x-argus header and writes its fields,
+// named by the built-in argus.proto.
+//
+//export ttsig_decode_argus
+func ttsig_decode_argus(header *C.char, outJSON **C.char) C.int {
+	return respond(outJSON, func() (any, error) {
+		pb, err := signer.Decrypt(C.GoString(header))
+		if err != nil {
+			return nil, err
+		}
+		msg, err := pb.DecodeWith(signer.ArgusDescriptor())
+		if err != nil {
+			return nil, err
+		}
+		return decodedJSON(msg), nil
+	})
+}
+
+// ttsig_decode_ladon decrypts an x-ladon header; aid is needed to derive
+// its key.
+//
+//export ttsig_decode_ladon
+func ttsig_decode_ladon(header *C.char, aid C.longlong, outJSON **C.char) C.int {
+	return respond(outJSON, func() (any, error) {
+		f, err := signer.DecodeLadon(C.GoString(header), int64(aid))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"random":     hex.EncodeToString(f.Random),
+			"plaintext":  f.Plaintext,
+			"khronos":    f.Khronos,
+			"license_id": f.LicenseID,
+			"aid":        f.AID,
+		}, nil
+	})
+}
+
+// ttsig_decode_gorgon decodes an x-gorgon header.
+//
+//export ttsig_decode_gorgon
+func ttsig_decode_gorgon(header *C.char, outJSON **C.char) C.int {
+	return respond(outJSON, func() (any, error) {
+		f, err := signer.DecodeGorgon(C.GoString(header))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"version":     f.Version,
+			"unix":        f.Unix,
+			"query_hash":  hex.EncodeToString(f.QueryHash[:]),
+			"body_hash":   hex.EncodeToString(f.BodyHash[:]),
+			"cookie_hash": hex.EncodeToString(f.CookieHash[:]),
+			"constant":    hex.EncodeToString(f.Constant[:]),
+		}, nil
+	})
+}
+
+// ttsig_free releases a string returned by the library.
+//
+//export ttsig_free
+func ttsig_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// respond runs fn and stores its JSON-encoded result, or the error, in
+// *out. A panic is reported as an error rather than crashing the host.
+func respond(out **C.char, fn func() (any, error)) (code C.int) {
+	defer func() {
+		if r := recover(); r != nil {
+			code = fail(out, fmt.Errorf("panic: %v", r))
+		}
+	}()
+	if out == nil {
+		return -1
+	}
+
+	v, err := fn()
+	if err != nil {
+		return fail(out, err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fail(out, err)
+	}
+	*out = C.CString(string(data))
+	return 0
+}
+
+func fail(out **C.char, err error) C.int {
+	if out != nil {
+		data, _ := json.Marshal(map[string]string{"error": err.Error()})
+		*out = C.CString(string(data))
+	}
+	return -1
+}
+
+// decodedJSON flattens a decoded message into JSON-friendly values: one
+// object per field with its number, name, type and value.
+func decodedJSON(msg *signer.DecodedMessage) []map[string]any {
+	out := make([]map[string]any, 0, len(msg.Fields))
+	for _, f := range msg.Fields {
+		m := map[string]any{
+			"number": f.Number,
+			"name":   f.Name(),
+			"type":   f.TypeName(),
+		}
+		switch v := f.Value.(type) {
+		case []byte:
+			m["value"] = hex.EncodeToString(v)
+		default:
+			if f.Message != nil {
+				m["value"] = decodedJSON(f.Message)
+			} else {
+				m["value"] = v
+			}
+		}
+		if f.Err != nil {
+			m["error"] = f.Err.Error()
+		}
+		out = append(out, m)
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestSharedLibrary builds the c-shared library, links testdata/sign.c
+// against it and runs the result.
+func TestSharedLibrary(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a shared library")
+	}
+	goTool := filepath.Join(runtime.GOROOT(), "bin", "go")
+	out, err := exec.Command(goTool, "env", "CGO_ENABLED", "CC").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := strings.Fields(string(out))
+	if len(env) != 2 || env[0] != "1" {
+		t.Skip("cgo is disabled")
+	}
+	cc, err := exec.LookPath(env[1])
+	if err != nil {
+		t.Skipf("no C compiler: %v", err)
+	}
+
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libttsig.so")
+	build := exec.Command(goTool, "build", "-buildmode=c-shared", "-o", lib, ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+
+	bin := filepath.Join(dir, "sign")
+	compile := exec.Command(cc, "-I", dir, "-o", bin, filepath.Join("testdata", "sign.c"), lib)
+	if out, err := compile.CombinedOutput(); err != nil {
+		t.Fatalf("cc: %v\n%s", err, out)
+	}
+
+	run := exec.Command(bin)
+	run.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir, "DYLD_LIBRARY_PATH="+dir)
+	out, err = run.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sign exited with %v:\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		`ttsig_sign: {`,
+		`"x-argus":"`,
+		`ttsig_decode_argus: [{"name":"magic","number":1,`,
+		`{"name":"device_id","number":5,"type":"string","value":"7"}`,
+		`ttsig_decode_ladon: {"aid":1233,`,
+		`ttsig_decode_gorgon: {"body_hash":"`,
+		`ttsig_sign (invalid): {"error":"RawParams must not be empty unless DeviceID is set"}`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output lacks %s", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("output:\n%s", out)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+ * Links libttsig and exercises every export. Build and run from the
+ * repository root:
+ *
+ *   go build -buildmode=c-shared -o /tmp/libttsig.so ./cmd/libttsig
+ *   cc -I/tmp -o /tmp/sign cmd/libttsig/testdata/sign.c /tmp/libttsig.so
+ *   LD_LIBRARY_PATH=/tmp /tmp/sign
+ *
+ * It exits non-zero if any call misbehaves.
+ */
+#include <stdio.h>
+#include <string.h>
+
+#include "libttsig.h"
+
+static int failures;
+
+static void check(const char *what, int got, int want, char *out) {
+	printf("%s: %s\n", what, out ? out : "(null)");
+	if (got != want || out == NULL) {
+		fprintf(stderr, "%s: returned %d, want %d\n", what, got, want);
+		failures++;
+	}
+}
+
+/* header extracts the value of "name" from a flat JSON object of strings. */
+static int header(const char *json, const char *name, char *buf, size_t len) {
+	char key[64];
+	snprintf(key, sizeof key, "\"%s\":\"", name);
+	const char *p = strstr(json, key);
+	if (p == NULL) {
+		return -1;
+	}
+	p += strlen(key);
+	const char *end = strchr(p, '"');
+	if (end == NULL || (size_t)(end - p) >= len) {
+		return -1;
+	}
+	memcpy(buf, p, end - p);
+	buf[end - p] = '\0';
+	return 0;
+}
+
+int main(void) {
+	char *out = NULL;
+	char argus[1024], ladon[256], gorgon[128];
+
+	int rc = ttsig_sign(
+		"{\"RawRequestParameters\": \"device_id=7&aid=1233\", \"RequestPayload\": \"a=1\"}",
+		&out);
+	check("ttsig_sign", rc, 0, out);
+	if (rc != 0 ||
+	    header(out, "x-argus", argus, sizeof argus) != 0 ||
+	    header(out, "x-ladon", ladon, sizeof ladon) != 0 ||
+	    header(out, "x-gorgon", gorgon, sizeof gorgon) != 0) {
+		fprintf(stderr, "missing signature headers\n");
+		return 1;
+	}
+	ttsig_free(out);
+
+	rc = ttsig_decode_argus(argus, &out);
+	check("ttsig_decode_argus", rc, 0, out);
+	ttsig_free(out);
+
+	rc = ttsig_decode_ladon(ladon, 1233, &out);
+	check("ttsig_decode_ladon", rc, 0, out);
+	ttsig_free(out);
+
+	rc = ttsig_decode_gorgon(gorgon, &out);
+	check("ttsig_decode_gorgon", rc, 0, out);
+	ttsig_free(out);
+
+	rc = ttsig_sign("{\"RawRequestParameters\": \"\"}", &out);
+	check("ttsig_sign (invalid)", rc, -1, out);
+	ttsig_free(out);
+
+	return failures ? 1 : 0;
+}
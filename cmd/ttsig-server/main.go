@@ -0,0 +1,64 @@
+// Command ttsig-server exposes SignRequest over HTTP for services that
+// cannot link the Go library.
+//
+//	POST /v1/sign     JSON SignConfig in, signed headers out
+//	POST /v1/verify   JSON SignConfig plus headers in, per-header result out
+//	GET  /healthz     liveness probe
+//	GET  /metrics     Prometheus text exposition
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8090", "address to listen on")
+	maxBody := flag.Int64("max-body", 1<<20, "maximum request body size in bytes")
+	profilesPath := flag.String("profiles", "", "JSON file of named SignConfig defaults")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "grace period for in-flight requests")
+	flag.Parse()
+
+	profiles := defaultProfiles()
+	if *profilesPath != "" {
+		loaded, err := loadProfiles(*profilesPath)
+		if err != nil {
+			log.Fatalf("ttsig-server: %v", err)
+		}
+		for name, p := range loaded {
+			profiles[name] = p
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           newServer(profiles, *maxBody).routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("ttsig-server: listening on %s", *listen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("ttsig-server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("ttsig-server: shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ttsig-server: shutdown: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Skill/ttsig"
+)
+
+// profile holds the defaults a client can select by name instead of
+// repeating app constants in every request.
+type profile struct {
+	AppID            int
+	LicenseID        int
+	SdkVersionString string
+	SdkVersionInt    int
+	Platform         ttsig.Platform
+}
+
+// defaultProfiles offers the built-in Android profile as "android".
+func defaultProfiles() map[string]profile {
+	p, _ := ttsig.PlatformAndroid.Profile()
+	return map[string]profile{
+		"android": {
+			AppID:            p.AppID,
+			LicenseID:        p.LicenseID,
+			SdkVersionString: p.SdkVersionString,
+			SdkVersionInt:    p.SdkVersionInt,
+			Platform:         ttsig.PlatformAndroid,
+		},
+	}
+}
+
+func loadProfiles(path string) (map[string]profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]profile
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return out, nil
+}
+
+// defaultProfile names the profile for a request that selects none: its
+// platform, from the config or the query's device_platform, or android.
+func defaultProfile(cfg ttsig.SignConfig) string {
+	platform := cfg.Platform
+	if platform == ttsig.PlatformUnset {
+		platform, _ = ttsig.ParsePlatform(requestQuery(cfg).DevicePlatform())
+	}
+	if platform == ttsig.PlatformUnset {
+		platform = ttsig.PlatformAndroid
+	}
+	return platform.String()
+}
+
+// requestQuery returns the query of cfg, or an empty one.
+func requestQuery(cfg ttsig.SignConfig) *ttsig.Query {
+	if cfg.Query != nil {
+		return cfg.Query
+	}
+	q, err := ttsig.ParseQuery(cfg.RawRequestParameters)
+	if err != nil {
+		return ttsig.NewQuery()
+	}
+	return q
+}
+
+// apply fills the zero fields of cfg from the profile. The aid is left to
+// the query when it carries one, so a profile never contradicts the URL.
+func (p profile) apply(cfg *ttsig.SignConfig) {
+	if cfg.AppID == 0 && requestQuery(*cfg).Get("aid") == "" {
+		cfg.AppID = p.AppID
+	}
+	if cfg.LicenseID == 0 {
+		cfg.LicenseID = p.LicenseID
+	}
+	if cfg.SdkVersionString == "" {
+		cfg.SdkVersionString = p.SdkVersionString
+	}
+	if cfg.SdkVersionInt == 0 {
+		cfg.SdkVersionInt = p.SdkVersionInt
+	}
+	if cfg.Platform == ttsig.PlatformUnset {
+		cfg.Platform = p.Platform
+	}
+}
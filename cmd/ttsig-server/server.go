@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+type server struct {
+	profiles map[string]profile
+	maxBody  int64
+
+	// metrics holds the signing stage metrics and the server's own
+	// counters, and writes both to /metrics.
+	metrics  *ttsig.PrometheusObserver
+	requests *ttsig.CounterVec
+	errors   *ttsig.CounterVec
+}
+
+func newServer(profiles map[string]profile, maxBody int64) *server {
+	m := ttsig.NewPrometheusObserver()
+	return &server{
+		profiles: profiles,
+		maxBody:  maxBody,
+		metrics:  m,
+		requests: m.NewCounter("ttsig_http_requests_total", "HTTP requests by route and status code.", "route", "code"),
+		errors:   m.NewCounter("ttsig_errors_total", "Failed requests by reason.", "reason"),
+	}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/sign", s.handleSign)
+	mux.HandleFunc("POST /v1/verify", s.handleVerify)
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	return s.instrument(mux)
+}
+
+// statusRecorder captures the response code for the request counter.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// instrument counts requests by the mux pattern they matched. The path
+// itself is client-supplied and would add a series per distinct URL.
+func (s *server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		s.requests.Inc(route, strconv.Itoa(rec.code))
+	})
+}
+
+// signRequest is the /v1/sign body: a SignConfig plus an optional profile name.
+type signRequest struct {
+	Profile string `json:"profile"`
+	ttsig.SignConfig
+}
+
+// verifyRequest is the /v1/verify body: the config the headers were built
+// from and the headers to check.
+type verifyRequest struct {
+	signRequest
+	Headers map[string]string `json:"headers"`
+}
+
+type headerResult struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type verifyResponse struct {
+	OK      bool                    `json:"ok"`
+	Results map[string]headerResult `json:"results"`
+}
+
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metrics.WriteMetrics(w)
+}
+
+func (s *server) handleSign(w http.ResponseWriter, r *http.Request) {
+	var req signRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	cfg, ok := s.resolve(w, req)
+	if !ok {
+		return
+	}
+
+	headers, err := ttsig.SignRequest(cfg)
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "sign", err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, headers)
+}
+
+func (s *server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req verifyRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	cfg, ok := s.resolve(w, req.signRequest)
+	if !ok {
+		return
+	}
+
+	// Re-sign at the captured time so the deterministic headers line up.
+	if ticket, err := strconv.ParseInt(req.Headers["x-ss-req-ticket"], 10, 64); err == nil {
+		cfg.Timestamp = time.UnixMilli(ticket)
+	} else if khronos, err := strconv.ParseInt(req.Headers["x-khronos"], 10, 64); err == nil {
+		cfg.Timestamp = time.Unix(khronos, 0)
+	} else {
+		s.fail(w, http.StatusBadRequest, "verify", errors.New("headers must include x-khronos or x-ss-req-ticket"))
+		return
+	}
+
+	expected, err := ttsig.SignRequest(cfg)
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "sign", err)
+		return
+	}
+
+	resolved, err := cfg.Resolve()
+	if err != nil {
+		s.fail(w, http.StatusBadRequest, "sign", err)
+		return
+	}
+
+	resp := verifyResponse{OK: true, Results: make(map[string]headerResult)}
+	for _, m := range ttsig.CompareHeadersWithKeys(resolved.Keys, expected, req.Headers, resolved.AppID) {
+		resp.Results[m.Name] = headerResult{OK: m.OK, Detail: m.Detail}
+		resp.OK = resp.OK && m.OK
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decode reads a size-limited JSON body into v, writing the error response
+// itself when it fails.
+func (s *server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.fail(w, http.StatusRequestEntityTooLarge, "too_large", err)
+			return false
+		}
+		s.fail(w, http.StatusBadRequest, "bad_json", err)
+		return false
+	}
+	return true
+}
+
+// resolve applies a profile to the config: the requested one or, without
+// one, the profile named after the request's platform (android unless the
+// config or query names another). A profiles file that lacks that default
+// leaves SignRequest's built-in defaults in place.
+func (s *server) resolve(w http.ResponseWriter, req signRequest) (ttsig.SignConfig, bool) {
+	cfg := req.SignConfig
+	cfg.Observer = s.metrics
+	if req.Profile == "" {
+		if p, ok := s.profiles[defaultProfile(cfg)]; ok {
+			p.apply(&cfg)
+		}
+		return cfg, true
+	}
+	p, ok := s.profiles[req.Profile]
+	if !ok {
+		s.fail(w, http.StatusBadRequest, "unknown_profile", fmt.Errorf("unknown profile %q", req.Profile))
+		return ttsig.SignConfig{}, false
+	}
+	p.apply(&cfg)
+	return cfg, true
+}
+
+func (s *server) fail(w http.ResponseWriter, code int, reason string, err error) {
+	s.errors.Inc(reason)
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
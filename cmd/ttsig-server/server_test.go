@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/signer"
+)
+
+const testQuery = "device_id=7300000000000000001&aid=1233&version_name=39.6.3"
+
+func newTestServer(t *testing.T, profiles map[string]profile) *httptest.Server {
+	t.Helper()
+	if profiles == nil {
+		profiles = defaultProfiles()
+	}
+	ts := httptest.NewServer(newServer(profiles, 1<<20).routes())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, ts *httptest.Server, path, body string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestSign(t *testing.T) {
+	ts := newTestServer(t, nil)
+	code, body := post(t, ts, "/v1/sign", `{
+		"RawRequestParameters": "`+testQuery+`",
+		"RequestPayload": "a=b",
+		"Cookie": "sid=1",
+		"Timestamp": "2023-11-14T22:13:20.123Z"
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	var got ttsig.SignedHeaders
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ttsig.SignRequest(ttsig.SignConfig{
+		RawRequestParameters: testQuery,
+		RequestPayload:       "a=b",
+		Cookie:               "sid=1",
+		Timestamp:            time.UnixMilli(1700000000123),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range ttsig.CompareHeaders(want, got, 1233) {
+		if !m.OK {
+			t.Errorf("%s: %s", m.Name, m.Detail)
+		}
+	}
+	if got["x-khronos"] != "1700000000" || got["x-ss-req-ticket"] != "1700000000123" {
+		t.Errorf("x-khronos %s, x-ss-req-ticket %s", got["x-khronos"], got["x-ss-req-ticket"])
+	}
+}
+
+func TestSignMissingDeviceID(t *testing.T) {
+	ts := newTestServer(t, nil)
+	code, body := post(t, ts, "/v1/sign", `{"RawRequestParameters": "aid=1233"}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400: %s", code, body)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp["error"], "device_id") {
+		t.Errorf("error %q does not mention device_id", resp["error"])
+	}
+}
+
+func TestSignProfile(t *testing.T) {
+	ts := newTestServer(t, map[string]profile{
+		"custom": {LicenseID: 42, SdkVersionString: "v05.01.00-ov-android", SdkVersionInt: 167776512},
+	})
+
+	code, body := post(t, ts, "/v1/sign", `{"profile": "custom", "RawRequestParameters": "`+testQuery+`"}`)
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	var got ttsig.SignedHeaders
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	ladon, err := signer.DecodeLadon(got["x-ladon"], 1233)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ladon.LicenseID != 42 {
+		t.Errorf("lc_id %d, want 42 from the profile", ladon.LicenseID)
+	}
+	pb, err := signer.Decrypt(got["x-argus"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdk, _ := pb.GetUtf8(8); sdk != "v05.01.00-ov-android" {
+		t.Errorf("sdk version %q, want the profile's", sdk)
+	}
+
+	code, body = post(t, ts, "/v1/sign", `{"profile": "nope", "RawRequestParameters": "`+testQuery+`"}`)
+	if code != http.StatusBadRequest || !strings.Contains(string(body), "unknown profile") {
+		t.Errorf("unknown profile: status %d: %s", code, body)
+	}
+}
+
+func getMetrics(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMetricsUnmatchedPaths(t *testing.T) {
+	ts := newTestServer(t, nil)
+	for _, path := range []string{"/random/a1", "/random/b2?x=1"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	metrics := getMetrics(t, ts)
+	if want := `ttsig_http_requests_total{route="unmatched",code="404"} 2`; !strings.Contains(metrics, want) {
+		t.Errorf("/metrics lacks %s:\n%s", want, metrics)
+	}
+	if strings.Contains(metrics, "/random/") {
+		t.Errorf("/metrics has a series per path:\n%s", metrics)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ts := newTestServer(t, nil)
+	post(t, ts, "/v1/sign", `{"RawRequestParameters": "`+testQuery+`"}`)
+	post(t, ts, "/v1/sign", `{"RawRequestParameters": "aid=1233"}`)
+	post(t, ts, "/v1/sign", `{not json`)
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := string(data)
+
+	for _, want := range []string{
+		`ttsig_http_requests_total{route="POST /v1/sign",code="200"} 1`,
+		`ttsig_http_requests_total{route="POST /v1/sign",code="400"} 2`,
+		`ttsig_errors_total{reason="bad_json"} 1`,
+		`ttsig_errors_total{reason="sign"} 1`,
+		`ttsig_stage_duration_seconds_count{stage="sign"} 2`,
+		`ttsig_stage_errors_total{stage="sign",reason="no_device_id"} 1`,
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("/metrics lacks %s", want)
+		}
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type %q", ct)
+	}
+}
+
+func TestSignDefaultProfile(t *testing.T) {
+	// A profiles file may override the built-in android profile; requests
+	// that name no profile still get it.
+	profiles := defaultProfiles()
+	profiles["android"] = profile{AppID: 1233, LicenseID: 42, Platform: ttsig.PlatformAndroid}
+	ts := newTestServer(t, profiles)
+
+	tests := []struct {
+		query     string
+		aid       int64
+		licenseID int64
+	}{
+		{testQuery, 1233, 42},
+		// The query's aid wins over the profile's.
+		{"device_id=7300000000000000001&aid=1128", 1128, 42},
+	}
+	for _, tt := range tests {
+		code, body := post(t, ts, "/v1/sign", `{"RawRequestParameters": "`+tt.query+`"}`)
+		if code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tt.query, code, body)
+			continue
+		}
+		var got ttsig.SignedHeaders
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+		ladon, err := signer.DecodeLadon(got["x-ladon"], tt.aid)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if ladon.LicenseID != tt.licenseID {
+			t.Errorf("%s: lc_id %d, want %d", tt.query, ladon.LicenseID, tt.licenseID)
+		}
+	}
+
+	// An iOS query must not fall back to the android profile.
+	code, body := post(t, ts, "/v1/sign", `{"RawRequestParameters": "device_id=7300000000000000001&device_platform=iphone"}`)
+	if code != http.StatusBadRequest || !strings.Contains(string(body), "is not supported") {
+		t.Errorf("iphone query: status %d: %s", code, body)
+	}
+}
@@ -0,0 +1,64 @@
+// Command ttsig-wasm signs one request per run: it reads a JSON SignConfig
+// on stdin and writes the signed headers as JSON on stdout. It is meant to
+// be built as a WASI module for sandboxes and edge workers:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -o ttsig.wasm ./cmd/ttsig-wasm
+//
+// The module only needs stdin, stdout and stderr. Time and randomness come
+// from WASI clock_time_get and random_get unless the input supplies them:
+// "Timestamp" (RFC 3339) fixes the signing time and "rand" (hex, 8 bytes)
+// fixes the random bytes of x-ladon and x-argus, so a host can make the
+// output deterministic.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Skill/ttsig"
+)
+
+type input struct {
+	ttsig.SignConfig
+
+	// Rand is hex-encoded randomness; see SignConfig.Rand.
+	Rand string `json:"rand"`
+}
+
+func main() {
+	if err := run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ttsig-wasm:", err)
+		os.Exit(1)
+	}
+}
+
+func run(r io.Reader, w io.Writer) error {
+	var in input
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return fmt.Errorf("parsing input: %w", err)
+	}
+
+	cfg := in.SignConfig
+	if in.Rand != "" {
+		b, err := hex.DecodeString(in.Rand)
+		if err != nil {
+			return fmt.Errorf("rand: %w", err)
+		}
+		if len(b) != 8 {
+			return fmt.Errorf("rand: need 8 bytes, got %d", len(b))
+		}
+		cfg.Rand = bytes.NewReader(b)
+	}
+
+	headers, err := ttsig.SignRequest(cfg)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(headers)
+}
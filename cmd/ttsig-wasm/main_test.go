@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/Skill/ttsig"
+)
+
+// buildModule compiles this command for wasip1 and returns the module bytes.
+func buildModule(t *testing.T) []byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a wasm module")
+	}
+	wasm := filepath.Join(t.TempDir(), "ttsig.wasm")
+	cmd := exec.Command(filepath.Join(runtime.GOROOT(), "bin", "go"), "build", "-o", wasm, ".")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(wasm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// runModule runs the module with stdin and returns its exit code, stdout
+// and stderr. The wall clock and random source are fixed; the monotonic
+// clock is left to wazero, since the Go runtime needs it to advance.
+func runModule(t *testing.T, rt wazero.Runtime, mod wazero.CompiledModule, stdin string) (uint32, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cfg := wazero.NewModuleConfig().
+		WithStdin(strings.NewReader(stdin)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithRandSource(bytes.NewReader(make([]byte, 1<<16))).
+		WithWalltime(func() (int64, int32) { return 1700000000, 0 }, sys.ClockResolution(time.Microsecond)).
+		WithName("")
+
+	_, err := rt.InstantiateModule(context.Background(), mod, cfg)
+	if err == nil {
+		return 0, stdout.String(), stderr.String()
+	}
+	exit, ok := err.(*sys.ExitError)
+	if !ok {
+		t.Fatal(err)
+	}
+	return exit.ExitCode(), stdout.String(), stderr.String()
+}
+
+func TestWASIModule(t *testing.T) {
+	wasm := buildModule(t)
+
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	mod, err := rt.CompileModule(ctx, wasm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const query = "device_id=7300000000000000001&aid=1233&version_name=39.6.3"
+	code, stdout, stderr := runModule(t, rt, mod, `{
+		"RawRequestParameters": "`+query+`",
+		"RequestPayload": "user_id=1&type=1",
+		"Cookie": "sessionid=5f1c3a",
+		"Timestamp": "2023-11-14T22:13:20.123Z",
+		"rand": "0102030405060708"
+	}`)
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	var got ttsig.SignedHeaders
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output %q: %v", stdout, err)
+	}
+
+	want, err := ttsig.SignRequest(ttsig.SignConfig{
+		RawRequestParameters: query,
+		RequestPayload:       "user_id=1&type=1",
+		Cookie:               "sessionid=5f1c3a",
+		Timestamp:            time.UnixMilli(1700000000123),
+		Rand:                 bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d headers, want %d", len(got), len(want))
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %s, SignRequest gives %s", name, got[name], v)
+		}
+	}
+
+	code, _, stderr = runModule(t, rt, mod, `{"RawRequestParameters": "aid=1233"}`)
+	if code != 1 || !strings.Contains(stderr, "ttsig-wasm:") {
+		t.Errorf("invalid input: exit code %d, stderr %q", code, stderr)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/signer"
+)
+
+func runArgus(args []string) error {
+	if len(args) == 0 || args[0] != "diff" {
+		return errors.New("usage: ttsig argus diff --captured <x-argus> --config cfg.json")
+	}
+	return runArgusDiff(args[1:])
+}
+
+// runArgusDiff decrypts a captured x-argus, signs the same request
+// ourselves and reports where the two beans disagree. The random field is
+// expected to differ and the captured create_time is reused, so what is
+// left points at fields a new app version added, dropped or changed.
+func runArgusDiff(args []string) error {
+	fs := flag.NewFlagSet("argus diff", flag.ContinueOnError)
+	captured := fs.String("captured", "", "captured x-argus header value")
+	configPath := fs.String("config", "", "JSON SignConfig describing the captured request (query, payload, app constants)")
+	protoFile := fs.String("proto", "", ".proto file describing the x-argus bean (default: built-in argus.proto)")
+	message := fs.String("message", "Argus", "message type in --proto to decode x-argus as")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *captured == "" || *configPath == "" {
+		fs.Usage()
+		return errors.New("--captured and --config are required")
+	}
+
+	desc, err := loadDescriptor(*protoFile, *message)
+	if err != nil {
+		return err
+	}
+
+	var cfg ttsig.SignConfig
+	if err := readJSONFile(*configPath, &cfg); err != nil {
+		return err
+	}
+
+	capturedPB, err := signer.Decrypt(*captured)
+	if err != nil {
+		return fmt.Errorf("captured header: %w", err)
+	}
+	createTime, err := capturedPB.GetInt(12)
+	if err != nil {
+		return fmt.Errorf("captured header: create_time: %w", err)
+	}
+	cfg.Timestamp = time.Unix(int64(createTime>>1), 0)
+	cfg.UnixTimestamp = 0
+
+	headers, err := ttsig.SignRequest(cfg)
+	if err != nil {
+		return err
+	}
+	oursPB, err := signer.Decrypt(headers["x-argus"])
+	if err != nil {
+		return err
+	}
+
+	got, err := capturedPB.DecodeWith(desc)
+	if err != nil {
+		return err
+	}
+	want, err := oursPB.DecodeWith(desc)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tFIELD\tCAPTURED\tOURS")
+	reported := 0
+	for _, d := range signer.DiffDecoded(got, want) {
+		if d.Kind == signer.DiffValue && d.Want.Number == 3 && d.Path == d.Want.Name() {
+			continue // random
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Kind, fieldLabel(d), describeField(d.Got), describeField(d.Want))
+		reported++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if reported > 0 {
+		return fmt.Errorf("%d fields differ", reported)
+	}
+	fmt.Fprintln(stdout, "beans match (random field ignored)")
+	return nil
+}
+
+// fieldLabel is the diff path followed by the field number, which unknown
+// fields already use as their name.
+func fieldLabel(d signer.FieldDiff) string {
+	f := d.Want
+	if f == nil {
+		f = d.Got
+	}
+	if f.Desc == nil {
+		return d.Path
+	}
+	return fmt.Sprintf("%s (%d)", d.Path, f.Number)
+}
+
+// describeField renders a side of a FieldDiff as "type value".
+func describeField(f *signer.DecodedField) string {
+	switch {
+	case f == nil:
+		return "-"
+	case f.Err != nil:
+		return fmt.Sprintf("%s %s [%v]", f.TypeName(), signer.FormatValue(f.Value), f.Err)
+	case f.Message != nil:
+		return f.TypeName() + " {...}"
+	}
+	return f.TypeName() + " " + signer.FormatValue(f.Value)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+// TestArgusDiff signs the same request twice with different SDK version
+// strings: the diff must name that field and nothing else.
+func TestArgusDiff(t *testing.T) {
+	cfg := ttsig.SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1233&version_name=32.1.0&cursor=0",
+		Timestamp:            time.Unix(1700000000, 0),
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := ttsig.SignRequest(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCommand(t, runArgusDiff, "", "--captured", same["x-argus"], "--config", configPath)
+	if err != nil || !strings.Contains(out, "beans match") {
+		t.Errorf("same config: %v\n%s", err, out)
+	}
+
+	captured := cfg
+	captured.SdkVersionString = "v05.01.00-ov-android"
+	differs, err := ttsig.SignRequest(captured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCommand(t, runArgusDiff, "", "--captured", differs["x-argus"], "--config", configPath)
+	if err == nil || err.Error() != "1 fields differ" {
+		t.Errorf("error %v, want 1 fields differ", err)
+	}
+	r := rows(out)
+	if len(r) != 2 || !hasRow(r, `value sdk_version_str (8) string "v05.01.00-ov-android" string "v05.00.06-ov-android"`) {
+		t.Errorf("diff:\n%s", out)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/ttsigtest"
+)
+
+const curlURL = "https://api16-normal-c-useast1a.tiktokv.com/aweme/v1/feed/?device_id=7300000000000000001&aid=1233&cursor=0"
+
+// staleCurl returns a curl command for a request signed in 2020, as it
+// would be copied from an old capture.
+func staleCurl(t *testing.T) (string, ttsig.SignedHeaders) {
+	t.Helper()
+	req, err := http.NewRequest("POST", curlURL, strings.NewReader("count=20"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", ttsig.ContentTypeForm)
+	old, err := ttsig.SignHTTPRequest(req, ttsig.SignConfig{Timestamp: time.Unix(1600000000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := ttsig.FormatCurl(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd, old
+}
+
+func TestCurlResigns(t *testing.T) {
+	cmd, old := staleCurl(t)
+	out, err := runCommand(t, runCurl, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ttsig.ParseCurl(out)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+
+	for _, name := range []string{"x-argus", "x-ladon", "x-gorgon", "x-khronos"} {
+		got := req.Header.Values(name)
+		if len(got) != 1 || got[0] == old[name] {
+			t.Errorf("%s = %q, want one new value replacing %q", name, got, old[name])
+		}
+	}
+	rep, err := ttsig.Verify(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.OK() {
+		t.Errorf("re-signed request does not verify:\n%s", rep)
+	}
+}
+
+func TestCurlExec(t *testing.T) {
+	api := ttsigtest.NewServer()
+	defer api.Close()
+
+	cmd, _ := staleCurl(t)
+	out, err := runCommand(t, runCurl, cmd, "--exec", api.URL)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if !strings.HasPrefix(out, "HTTP/1.1 200 OK\n") {
+		t.Errorf("output:\n%s", out)
+	}
+	req := api.LastRequest()
+	if req == nil || !req.Accepted() || req.URL.Path != "/aweme/v1/feed/" {
+		t.Errorf("request not accepted: %+v", req)
+	}
+}
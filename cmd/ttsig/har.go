@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/har"
+)
+
+func runHar(args []string) error {
+	if len(args) == 0 || args[0] != "resign" {
+		return fmt.Errorf("usage: ttsig har resign [--config cfg.json] capture.har")
+	}
+	return runHarResign(args[1:])
+}
+
+func runHarResign(args []string) error {
+	fs := flag.NewFlagSet("har resign", flag.ContinueOnError)
+	configPath := fs.String("config", "", "JSON SignConfig supplying app constants (aid, lc_id, sdk version, sec_device_id)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: ttsig har resign [--config cfg.json] capture.har")
+	}
+
+	var base ttsig.SignConfig
+	if *configPath != "" {
+		if err := readJSONFile(*configPath, &base); err != nil {
+			return err
+		}
+	}
+
+	f, err := har.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	results, err := har.Resign(f, base)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(tw, "#%d %s %s\n", r.Index, r.Method, r.URL)
+		for _, h := range r.Headers {
+			status := "match"
+			if !h.OK {
+				status = "MISMATCH"
+			}
+			if h.Decoded {
+				status += " (decoded)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", h.Name, status, h.Detail)
+		}
+		if !r.OK() {
+			failed++
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d entries re-signed, %d with mismatches\n", len(results), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d entries did not match", failed)
+	}
+	return nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/signer"
+)
+
+func runInspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	argus := fs.String("argus", "", "x-argus header value")
+	ladon := fs.String("ladon", "", "x-ladon header value")
+	gorgon := fs.String("gorgon", "", "x-gorgon header value")
+	aid := fs.Int64("aid", 0, "app id used to derive the x-ladon key (default: from x-argus, then the query, then 1233)")
+	protoFile := fs.String("proto", "", ".proto file describing the x-argus bean (default: built-in argus.proto)")
+	message := fs.String("message", "Argus", "message type in --proto to decode x-argus as")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ttsig inspect [--argus v] [--ladon v] [--gorgon v] [--aid n] [--proto f --message m]")
+		fmt.Fprintln(fs.Output(), "       ttsig inspect < request.txt")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Without header flags a raw HTTP request is read from stdin.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var query string
+	if *argus == "" && *ladon == "" && *gorgon == "" {
+		req, err := readRequest(stdin)
+		if err != nil {
+			return err
+		}
+		*argus = req.Header.Get("x-argus")
+		*ladon = req.Header.Get("x-ladon")
+		*gorgon = req.Header.Get("x-gorgon")
+		query = req.URL.RawQuery
+	}
+
+	desc, err := loadDescriptor(*protoFile, *message)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	printInspection(tw, inspection{
+		argus:  *argus,
+		ladon:  *ladon,
+		gorgon: *gorgon,
+		query:  query,
+		aid:    *aid,
+	}, desc)
+	return tw.Flush()
+}
+
+// loadDescriptor returns message from protoFile, or the built-in Argus
+// descriptor when protoFile is empty.
+func loadDescriptor(protoFile, message string) (*signer.MessageDescriptor, error) {
+	if protoFile == "" {
+		return signer.ArgusDescriptor(), nil
+	}
+	src, err := os.ReadFile(protoFile)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := signer.ParseProto(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", protoFile, err)
+	}
+	desc := fd.Message(message)
+	if desc == nil {
+		return nil, fmt.Errorf("%s: no message %q", protoFile, message)
+	}
+	return desc, nil
+}
+
+// readRequest parses a raw HTTP/1.x request; only its URL and headers
+// are used.
+func readRequest(r io.Reader) (*http.Request, error) {
+	req, err := http.ReadRequest(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("reading request: %w", err)
+	}
+	req.Body.Close()
+	return req, nil
+}
+
+// inspection is what printInspection decodes. aid, when not zero,
+// overrides the aid the x-ladon key is derived from.
+type inspection struct {
+	argus, ladon, gorgon string
+	query                string
+	aid                  int64
+}
+
+// ladonAID returns the aid x-ladon is keyed with and where it came from,
+// looked up like Verify does: the Argus bean, the query, then
+// ttsig.DefaultAppID.
+func ladonAID(bean *signer.ProtoBuf, query string) (int64, string) {
+	if bean != nil {
+		s, _ := bean.GetUtf8(4)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && n != 0 {
+			return n, "x-argus"
+		}
+	}
+	if q, err := ttsig.ParseQuery(query); err == nil {
+		if n, err := q.AID(); err == nil && n != 0 {
+			return int64(n), "query"
+		}
+	}
+	return ttsig.DefaultAppID, "default"
+}
+
+// printInspection writes one "header field value" row per decoded field.
+// Decoding errors are reported inline so the other headers still print.
+func printInspection(w io.Writer, in inspection, desc *signer.MessageDescriptor) {
+	fmt.Fprintln(w, "HEADER\tFIELD\tVALUE")
+
+	var bean *signer.ProtoBuf
+	var argusErr error
+	if in.argus != "" {
+		bean, argusErr = signer.Decrypt(in.argus)
+	}
+
+	if in.gorgon != "" {
+		f, err := signer.DecodeGorgon(in.gorgon)
+		if err != nil {
+			fmt.Fprintf(w, "x-gorgon\terror\t%v\n", err)
+		} else {
+			fmt.Fprintf(w, "x-gorgon\tversion\t%s\n", f.Version)
+			fmt.Fprintf(w, "x-gorgon\tunix\t%d\n", f.Unix)
+			fmt.Fprintf(w, "x-gorgon\tquery md5[:4]\t%s\n", hex.EncodeToString(f.QueryHash[:]))
+			fmt.Fprintf(w, "x-gorgon\tbody md5[:4]\t%s\n", hex.EncodeToString(f.BodyHash[:]))
+			fmt.Fprintf(w, "x-gorgon\tcookie md5[:4]\t%s\n", hex.EncodeToString(f.CookieHash[:]))
+			fmt.Fprintf(w, "x-gorgon\tconstant\t%s\n", hex.EncodeToString(f.Constant[:]))
+		}
+	}
+
+	if in.ladon != "" {
+		aid, source := in.aid, "--aid"
+		if aid == 0 {
+			aid, source = ladonAID(bean, in.query)
+		}
+		fmt.Fprintf(w, "x-ladon\tkey aid\t%d (%s)\n", aid, source)
+		f, err := signer.DecodeLadon(in.ladon, aid)
+		if err != nil {
+			fmt.Fprintf(w, "x-ladon\terror\t%v\n", err)
+		} else {
+			fmt.Fprintf(w, "x-ladon\tplaintext\t%s\n", f.Plaintext)
+			fmt.Fprintf(w, "x-ladon\tkhronos\t%d\n", f.Khronos)
+			fmt.Fprintf(w, "x-ladon\tlc_id\t%d\n", f.LicenseID)
+			fmt.Fprintf(w, "x-ladon\taid\t%d\n", f.AID)
+			fmt.Fprintf(w, "x-ladon\trandom\t%s\n", hex.EncodeToString(f.Random))
+		}
+	}
+
+	if in.argus != "" {
+		if argusErr != nil {
+			fmt.Fprintf(w, "x-argus\terror\t%v\n", argusErr)
+		} else {
+			msg, err := bean.DecodeWith(desc)
+			if err != nil {
+				fmt.Fprintf(w, "x-argus\terror\t%v\n", err)
+				return
+			}
+			printDecoded(w, "", msg)
+		}
+	}
+}
+
+// printDecoded writes one x-argus row per field, naming nested fields by
+// their dotted path.
+func printDecoded(w io.Writer, prefix string, msg *signer.DecodedMessage) {
+	for _, f := range msg.Fields {
+		name := prefix + f.Name()
+		switch {
+		case f.Err != nil:
+			fmt.Fprintf(w, "x-argus\t%s (%d)\t%s [%v]\n", name, f.Number, signer.FormatValue(f.Value), f.Err)
+		case f.Message != nil:
+			printDecoded(w, name+".", f.Message)
+		default:
+			fmt.Fprintf(w, "x-argus\t%s (%d)\t%s\n", name, f.Number, signer.FormatValue(f.Value))
+		}
+	}
+}
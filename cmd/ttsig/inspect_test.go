@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+const inspectQuery = "device_id=7300000000000000001&aid=1128&device_platform=android&version_name=30.1.0"
+
+// inspectHeaders signs a request for aid 1128, whose x-ladon does not
+// decode with the default aid.
+func inspectHeaders(t *testing.T) ttsig.SignedHeaders {
+	t.Helper()
+	headers, err := ttsig.SignRequest(ttsig.SignConfig{
+		RawRequestParameters: inspectQuery,
+		Timestamp:            time.Unix(1700000000, 0),
+		Rand:                 bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return headers
+}
+
+// rows returns the whitespace-normalized lines of tabwriter output.
+func rows(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return lines
+}
+
+func hasRow(lines []string, row string) bool {
+	for _, l := range lines {
+		if l == row {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRowPrefix(lines []string, prefix string) bool {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestInspectStdin(t *testing.T) {
+	h := inspectHeaders(t)
+	req := "GET /aweme/v1/feed/?" + inspectQuery + " HTTP/1.1\r\n" +
+		"Host: api.example.com\r\n" +
+		"x-argus: " + h["x-argus"] + "\r\n" +
+		"x-ladon: " + h["x-ladon"] + "\r\n" +
+		"x-gorgon: " + h["x-gorgon"] + "\r\n\r\n"
+
+	out, err := runCommand(t, runInspect, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := rows(out)
+	for _, want := range []string{
+		"HEADER FIELD VALUE",
+		"x-gorgon unix 1700000000",
+		"x-ladon key aid 1128 (x-argus)",
+		"x-ladon plaintext 1700000000-1611921764-1128",
+		"x-ladon random 01020304",
+		"x-argus ms_app_id (4) \"1128\"",
+		"x-argus app_version (7) \"30.1.0\"",
+	} {
+		if !hasRow(lines, want) {
+			t.Errorf("missing row %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestInspectLadonAID(t *testing.T) {
+	h := inspectHeaders(t)
+	tests := []struct {
+		name string
+		in   inspection
+		want string
+	}{
+		{"from x-argus", inspection{argus: h["x-argus"], ladon: h["x-ladon"]}, "x-ladon key aid 1128 (x-argus)"},
+		{"from the query", inspection{ladon: h["x-ladon"], query: inspectQuery}, "x-ladon key aid 1128 (query)"},
+		{"flag overrides", inspection{argus: h["x-argus"], ladon: h["x-ladon"], aid: 1128}, "x-ladon key aid 1128 (--aid)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printInspection(&out, tt.in, nil)
+			lines := rows(out.String())
+			if !hasRow(lines, tt.want) || !hasRow(lines, "x-ladon aid 1128") {
+				t.Errorf("got\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestInspectFailures(t *testing.T) {
+	h := inspectHeaders(t)
+
+	// Without the bean or query the default aid derives the wrong key.
+	out, err := runCommand(t, runInspect, "", "--ladon", h["x-ladon"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := rows(out)
+	if !hasRow(lines, "x-ladon key aid 1233 (default)") || !hasRowPrefix(lines, "x-ladon error ladon:") {
+		t.Errorf("got\n%s", out)
+	}
+
+	out, err = runCommand(t, runInspect, "", "--argus", "AAAA", "--gorgon", "0404")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = rows(out)
+	if !hasRowPrefix(lines, "x-argus error") || !hasRowPrefix(lines, "x-gorgon error") {
+		t.Errorf("got\n%s", out)
+	}
+
+	if _, err := runCommand(t, runInspect, "not a request"); err == nil {
+		t.Error("a malformed request on stdin was accepted")
+	}
+}
@@ -0,0 +1,59 @@
+// Command ttsig is a toolbox around the ttsig signing library.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// stdin and stdout are what commands read and write; tests replace them.
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"inspect", "decode captured x-argus, x-ladon and x-gorgon headers", runInspect},
+	{"argus", "diff a captured x-argus bean against the one we build", runArgus},
+	{"curl", "re-sign a curl command line and print or send it", runCurl},
+	{"har", "re-sign the requests in a HAR capture and diff the headers", runHar},
+	{"proxy", "run an HTTP proxy that signs requests to configured hosts", runProxy},
+	{"vectors", "generate or check cross-implementation test vectors", runVectors},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ttsig <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "ttsig %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "ttsig: unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
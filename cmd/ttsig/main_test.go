@@ -0,0 +1,20 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// runCommand runs a command with input on stdin and returns what it wrote
+// to stdout.
+func runCommand(t *testing.T, run func([]string) error, input string, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	oldIn, oldOut := stdin, stdout
+	stdin, stdout = strings.NewReader(input), &out
+	t.Cleanup(func() { stdin, stdout = oldIn, oldOut })
+
+	err := run(args)
+	return out.String(), err
+}
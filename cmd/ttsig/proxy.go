@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+func runProxy(args []string) error {
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:8080", "address to listen on")
+	configPath := fs.String("config", "", "JSON SignConfig with the app constants to sign with")
+	hosts := fs.String("hosts", "", `comma-separated hosts to sign, subdomains included, or "all" (required)`)
+	upstream := fs.String("upstream", "", "send every request to this base URL instead, e.g. a local mock server")
+	insecure := fs.Bool("insecure", false, "skip TLS verification of upstream servers")
+	caCert := fs.String("ca-cert", "ttsig-ca.pem", "CA certificate for CONNECT interception, created if missing")
+	caKey := fs.String("ca-key", "ttsig-ca-key.pem", "CA private key, created if missing")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ttsig proxy --hosts h1,h2|all [--listen addr] [--config cfg.json] [--upstream url]")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "An HTTP proxy that signs requests to the configured hosts. CONNECT")
+		fmt.Fprintln(fs.Output(), "tunnels to those hosts are intercepted with a local CA, which clients")
+		fmt.Fprintln(fs.Output(), "must trust; use it in test setups only.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p := &proxy{log: log.New(os.Stderr, "", log.LstdFlags)}
+	if *configPath != "" {
+		if err := readJSONFile(*configPath, &p.base); err != nil {
+			return err
+		}
+	}
+	// Signing every host also intercepts every CONNECT tunnel, so it has
+	// to be asked for.
+	if strings.TrimSpace(*hosts) == "all" {
+		p.allHosts = true
+	} else {
+		for _, h := range strings.Split(*hosts, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				p.hosts = append(p.hosts, strings.ToLower(h))
+			}
+		}
+		if len(p.hosts) == 0 {
+			return errors.New("--hosts is required; pass --hosts all to sign requests to every host")
+		}
+	}
+	if *upstream != "" {
+		u, err := url.Parse(*upstream)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid --upstream %q", *upstream)
+		}
+		p.upstream = u
+	}
+	p.transport = &http.Transport{
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: *insecure},
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	ca, created, err := loadOrCreateCA(*caCert, *caKey)
+	if err != nil {
+		return err
+	}
+	if created {
+		p.log.Printf("created CA %s; trust it in the client to intercept HTTPS", *caCert)
+	}
+	p.ca = ca
+
+	srv := &http.Server{Addr: *listen, Handler: p}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	p.log.Printf("proxy listening on %s", *listen)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// proxy is a forward proxy that signs requests on their way out.
+type proxy struct {
+	base      ttsig.SignConfig
+	hosts     []string
+	allHosts  bool
+	upstream  *url.URL
+	transport *http.Transport
+	ca        *certAuthority
+	log       *log.Logger
+}
+
+// hopHeaders are connection-level headers a proxy must not forward.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// signs reports whether requests to host get signed.
+func (p *proxy) signs(host string) bool {
+	if p.allHosts {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, h := range p.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.connect(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "ttsig proxy: not a proxy request", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := p.forward(r)
+	if err != nil {
+		http.Error(w, "ttsig proxy: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, name := range hopHeaders {
+		resp.Header.Del(name)
+	}
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// forward signs r if its host is configured and sends it upstream. It
+// fails, without sending anything, when a request to such a host cannot
+// be signed.
+func (p *proxy) forward(r *http.Request) (*http.Response, error) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, name := range hopHeaders {
+		out.Header.Del(name)
+	}
+
+	if p.signs(out.URL.Hostname()) {
+		// A request to a signed host is never sent without its signature.
+		headers, err := ttsig.SignHTTPRequest(out, p.base)
+		if err != nil {
+			p.log.Printf("%s %s: not signed: %v", out.Method, out.URL, err)
+			return nil, fmt.Errorf("signing: %w", err)
+		}
+		p.log.Printf("%s %s: x-khronos=%s x-gorgon=%s x-ladon=%s x-argus=%s",
+			out.Method, out.URL, headers["x-khronos"], headers["x-gorgon"], headers["x-ladon"], headers["x-argus"])
+	}
+
+	if p.upstream != nil {
+		out.Host = ""
+		out.URL.Scheme = p.upstream.Scheme
+		out.URL.Host = p.upstream.Host
+		out.URL.Path = strings.TrimSuffix(p.upstream.Path, "/") + out.URL.Path
+		out.URL.RawPath = ""
+	}
+	return p.transport.RoundTrip(out)
+}
+
+// connect handles a CONNECT request: tunnels to unsigned hosts are passed
+// through untouched, tunnels to signed hosts are terminated with a leaf
+// certificate from the local CA so the requests inside can be signed.
+func (p *proxy) connect(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "ttsig proxy: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.signs(host) {
+		upstream, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+		if err != nil {
+			http.Error(w, "ttsig proxy: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+		return
+	}
+
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = host
+			}
+			return p.ca.leaf(name)
+		},
+		NextProtos: []string{"http/1.1"},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		p.log.Printf("CONNECT %s: TLS handshake: %v", r.Host, err)
+		return
+	}
+
+	br := bufio.NewReader(tlsConn)
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				p.log.Printf("CONNECT %s: %v", r.Host, err)
+			}
+			return
+		}
+		req.URL.Scheme = "https"
+		req.URL.Host = r.Host
+		req = req.WithContext(r.Context())
+
+		resp, err := p.forward(req)
+		if err != nil {
+			// The length lets the client read the error without waiting
+			// for the tunnel to close.
+			msg := "ttsig proxy: " + err.Error() + "\n"
+			resp = &http.Response{
+				StatusCode:    http.StatusBadGateway,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+				ContentLength: int64(len(msg)),
+				Body:          io.NopCloser(strings.NewReader(msg)),
+			}
+		}
+		for _, name := range hopHeaders {
+			resp.Header.Del(name)
+		}
+		err = resp.Write(tlsConn)
+		resp.Body.Close()
+		if err != nil || req.Close {
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Skill/ttsig/ttsigtest"
+)
+
+const proxyQuery = "device_id=7300000000000000001&aid=1233&cursor=0"
+
+// newTestProxy starts a proxy signing requests to hosts and sending them
+// all to upstream. It returns the proxy server and a client that uses it
+// and trusts its CA.
+func newTestProxy(t *testing.T, upstream string, hosts ...string) (*proxy, *http.Client) {
+	t.Helper()
+	u, err := url.Parse(upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ca, _, err := loadOrCreateCA(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &proxy{
+		hosts:     hosts,
+		upstream:  u,
+		transport: &http.Transport{},
+		ca:        ca,
+		log:       log.New(io.Discard, "", 0),
+	}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+
+	proxyURL, _ := url.Parse(srv.URL)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+	t.Cleanup(client.CloseIdleConnections)
+	return p, client
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestProxySignsConfiguredHosts(t *testing.T) {
+	api := ttsigtest.NewServer()
+	defer api.Close()
+	_, client := newTestProxy(t, api.URL+"/prefix", "example.com")
+
+	code, body := get(t, client, "http://api.example.com/aweme/v1/feed/?"+proxyQuery)
+	if code != http.StatusOK {
+		t.Fatalf("signed host: status %d: %s", code, body)
+	}
+	req := api.LastRequest()
+	if !req.Accepted() {
+		t.Errorf("signed request rejected: %s", req.Reason)
+	}
+	// --upstream keeps the path under its own.
+	if req.URL.Path != "/prefix/aweme/v1/feed/" || req.URL.RawQuery != proxyQuery {
+		t.Errorf("upstream got %s", req.URL)
+	}
+
+	code, _ = get(t, client, "http://other.test/aweme/v1/feed/?"+proxyQuery)
+	if code != http.StatusForbidden || api.LastRequest().Code != ttsigtest.CodeUnsigned {
+		t.Errorf("other host: status %d, want the unsigned request rejected upstream", code)
+	}
+}
+
+func TestProxySignFailure(t *testing.T) {
+	api := ttsigtest.NewServer()
+	defer api.Close()
+	_, client := newTestProxy(t, api.URL, "example.com")
+
+	// Without a device_id the request cannot be signed.
+	code, body := get(t, client, "http://api.example.com/aweme/v1/feed/?aid=1233")
+	if code != http.StatusBadGateway || !strings.Contains(body, "signing:") {
+		t.Errorf("status %d: %s", code, body)
+	}
+	if n := len(api.Requests()); n != 0 {
+		t.Errorf("%d requests reached the upstream unsigned", n)
+	}
+}
+
+func TestProxyConnect(t *testing.T) {
+	api := ttsigtest.NewServer()
+	defer api.Close()
+	_, client := newTestProxy(t, api.URL, "example.com")
+
+	// The tunnel is terminated with a leaf from the proxy CA and the
+	// request inside signed like a plain one.
+	code, body := get(t, client, "https://api.example.com/aweme/v1/feed/?"+proxyQuery)
+	if code != http.StatusOK {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	if req := api.LastRequest(); req == nil || !req.Accepted() {
+		t.Errorf("request through CONNECT not accepted: %+v", req)
+	}
+
+	code, body = get(t, client, "https://api.example.com/aweme/v1/feed/?aid=1233")
+	if code != http.StatusBadGateway || !strings.Contains(body, "signing:") {
+		t.Errorf("unsignable request through CONNECT: status %d: %s", code, body)
+	}
+}
+
+func TestProxySigns(t *testing.T) {
+	p := &proxy{hosts: []string{"tiktokv.com", "api.example.com"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"tiktokv.com", true},
+		{"api16-normal-c-useast1a.tiktokv.com", true},
+		{"API.TIKTOKV.COM", true},
+		{"nottiktokv.com", false},
+		{"tiktokv.com.evil.test", false},
+		{"api.example.com", true},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := p.signs(tt.host); got != tt.want {
+			t.Errorf("signs(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if !(&proxy{allHosts: true}).signs("anything.test") {
+		t.Error("--hosts all did not sign every host")
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+// vectorFile is the format written by generate and read by check. Other
+// implementations run each input and write the same structure back; check
+// compares every header and whichever intermediate fields they kept.
+type vectorFile struct {
+	Version int      `json:"version"`
+	Seed    uint64   `json:"seed"`
+	Vectors []vector `json:"vectors"`
+}
+
+type vector struct {
+	Input        vectorInput        `json:"input"`
+	Intermediate vectorIntermediate `json:"intermediate"`
+	Headers      map[string]string  `json:"headers"`
+}
+
+// vectorInput is fully resolved: nothing is left to defaults, so a port
+// does not need to know ours.
+type vectorInput struct {
+	Query            string `json:"query"`
+	Body             string `json:"body_hex"`
+	Cookie           string `json:"cookie"`
+	TimestampMillis  int64  `json:"timestamp_ms"`
+	Rand             string `json:"rand_hex"`
+	Platform         string `json:"platform"`
+	AppID            int    `json:"aid"`
+	LicenseID        int    `json:"license_id"`
+	DeviceID         string `json:"device_id"`
+	VersionName      string `json:"version_name"`
+	SdkVersionString string `json:"sdk_version_str"`
+	SdkVersionInt    int    `json:"sdk_version"`
+	SecDeviceID      string `json:"sec_device_id"`
+}
+
+// vectorResult is a vector as read back by check. The intermediates are
+// kept as a map so a value left out can be told from one filled in empty.
+type vectorResult struct {
+	Input        vectorInput       `json:"input"`
+	Intermediate map[string]string `json:"intermediate"`
+	Headers      map[string]string `json:"headers"`
+}
+
+type vectorIntermediate struct {
+	GorgonBase      string `json:"gorgon_base,omitempty"`
+	LadonPlaintext  string `json:"ladon_plaintext,omitempty"`
+	LadonKey        string `json:"ladon_key,omitempty"`
+	LadonCiphertext string `json:"ladon_ciphertext_hex,omitempty"`
+	ArgusProtobuf   string `json:"argus_protobuf_hex,omitempty"`
+	ArgusSimon      string `json:"argus_simon_hex,omitempty"`
+	ArgusWrapped    string `json:"argus_wrapped_hex,omitempty"`
+	ArgusAES        string `json:"argus_aes_hex,omitempty"`
+}
+
+// fields returns the intermediates in pipeline order under their JSON names.
+func (vi vectorIntermediate) fields() [][2]string {
+	return [][2]string{
+		{"gorgon_base", vi.GorgonBase},
+		{"ladon_plaintext", vi.LadonPlaintext},
+		{"ladon_key", vi.LadonKey},
+		{"ladon_ciphertext_hex", vi.LadonCiphertext},
+		{"argus_protobuf_hex", vi.ArgusProtobuf},
+		{"argus_simon_hex", vi.ArgusSimon},
+		{"argus_wrapped_hex", vi.ArgusWrapped},
+		{"argus_aes_hex", vi.ArgusAES},
+	}
+}
+
+func runVectors(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "generate":
+			return runVectorsGenerate(args[1:])
+		case "check":
+			return runVectorsCheck(args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "usage: ttsig vectors generate [--count n] [--seed s] [--out vectors.json]")
+	fmt.Fprintln(os.Stderr, "       ttsig vectors check results.json")
+	return errors.New("missing or unknown subcommand")
+}
+
+func runVectorsGenerate(args []string) error {
+	fs := flag.NewFlagSet("vectors generate", flag.ContinueOnError)
+	count := fs.Int("count", 100, "number of vectors")
+	seed := fs.Uint64("seed", 1, "seed; the same seed always produces the same file")
+	out := fs.String("out", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	rng := rand.New(rand.NewPCG(*seed, *seed^0x7474736967))
+	file := vectorFile{Version: 1, Seed: *seed}
+	for i := 0; i < *count; i++ {
+		v, err := computeVector(randomInput(rng))
+		if err != nil {
+			return fmt.Errorf("vector %d: %w", i, err)
+		}
+		file.Vectors = append(file.Vectors, *v)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *out == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
+
+func runVectorsCheck(args []string) error {
+	fs := flag.NewFlagSet("vectors check", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ttsig vectors check results.json")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Recomputes every vector from its input and compares the intermediate")
+		fmt.Fprintln(fs.Output(), "values the other implementation filled in, and every header. An")
+		fmt.Fprintln(fs.Output(), "intermediate value is skipped only when its key is left out.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected one results file")
+	}
+
+	var file struct {
+		Vectors []vectorResult `json:"vectors"`
+	}
+	if err := readJSONFile(fs.Arg(0), &file); err != nil {
+		return err
+	}
+	if len(file.Vectors) == 0 {
+		return fmt.Errorf("%s holds no vectors", fs.Arg(0))
+	}
+
+	failed := 0
+	for i, got := range file.Vectors {
+		want, err := computeVector(got.Input)
+		if err != nil {
+			fmt.Fprintf(stdout, "vector %d: %v\n", i, err)
+			failed++
+			continue
+		}
+		if diffs := diffVector(want, &got); len(diffs) > 0 {
+			failed++
+			fmt.Fprintf(stdout, "vector %d: first mismatch at %s\n", i, diffs[0])
+			for _, d := range diffs[1:] {
+				fmt.Fprintf(stdout, "  also %s\n", d)
+			}
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d/%d vectors match\n", len(file.Vectors)-failed, len(file.Vectors))
+	if failed > 0 {
+		return fmt.Errorf("%d vectors differ", failed)
+	}
+	return nil
+}
+
+// computeVector signs in and records every layer.
+func computeVector(in vectorInput) (*vector, error) {
+	body, err := hex.DecodeString(in.Body)
+	if err != nil {
+		return nil, fmt.Errorf("body_hex: %w", err)
+	}
+	rnd, err := hex.DecodeString(in.Rand)
+	if err != nil || len(rnd) != 8 {
+		return nil, fmt.Errorf("rand_hex must be 8 hex bytes, got %q", in.Rand)
+	}
+	platform, err := ttsig.ParsePlatform(in.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	tr, err := ttsig.TraceRequest(ttsig.SignConfig{
+		RawRequestParameters: in.Query,
+		Body:                 ttsig.NewBody(body),
+		Cookie:               in.Cookie,
+		Timestamp:            time.UnixMilli(in.TimestampMillis),
+		Rand:                 bytes.NewReader(rnd),
+		Platform:             platform,
+		AppID:                in.AppID,
+		LicenseID:            in.LicenseID,
+		DeviceID:             in.DeviceID,
+		VersionName:          in.VersionName,
+		SdkVersionString:     in.SdkVersionString,
+		SdkVersionInt:        in.SdkVersionInt,
+		SecDeviceID:          in.SecDeviceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &vector{
+		Input: in,
+		Intermediate: vectorIntermediate{
+			GorgonBase:      tr.GorgonBase,
+			LadonPlaintext:  tr.Ladon.Plaintext,
+			LadonKey:        tr.Ladon.Key,
+			LadonCiphertext: hex.EncodeToString(tr.Ladon.Ciphertext),
+			ArgusProtobuf:   hex.EncodeToString(tr.Argus.Protobuf),
+			ArgusSimon:      hex.EncodeToString(tr.Argus.Simon),
+			ArgusWrapped:    hex.EncodeToString(tr.Argus.Wrapped),
+			ArgusAES:        hex.EncodeToString(tr.Argus.AES),
+		},
+		Headers: tr.Headers,
+	}, nil
+}
+
+// diffVector lists the fields of got that differ from want, in pipeline
+// order, so the first entry is where the implementations diverge. An
+// intermediate got leaves out is skipped; a header it leaves out is not.
+func diffVector(want *vector, got *vectorResult) []string {
+	var diffs []string
+	for _, f := range want.Intermediate.fields() {
+		name, w := f[0], f[1]
+		if g, ok := got.Intermediate[name]; ok && !strings.EqualFold(w, g) {
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got %q", name, w, g))
+		}
+	}
+
+	names := make([]string, 0, len(want.Headers))
+	for name := range want.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Header values are case sensitive (base64), unlike the hex above.
+		g, ok := got.Headers[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, missing", name, want.Headers[name]))
+		case g != want.Headers[name]:
+			diffs = append(diffs, fmt.Sprintf("%s: want %s, got %q", name, want.Headers[name], g))
+		}
+	}
+	return diffs
+}
+
+// randomInput draws a request that exercises escaping and bodies of
+// different shapes.
+func randomInput(rng *rand.Rand) vectorInput {
+	platform := ttsig.PlatformAndroid
+	prof, _ := platform.Profile()
+
+	deviceID := strconv.FormatUint(7e18+rng.Uint64N(1e18), 10)
+	versionName := fmt.Sprintf("%d.%d.%d", 30+rng.IntN(10), rng.IntN(10), rng.IntN(10))
+
+	q := ttsig.NewQuery()
+	q.Add("device_id", deviceID)
+	q.Add("iid", strconv.FormatUint(7e18+rng.Uint64N(1e18), 10))
+	q.Add("aid", strconv.Itoa(prof.AppID))
+	q.Add("version_name", versionName)
+	q.Add("device_platform", prof.DevicePlatforms[0])
+	for i, n := 0, rng.IntN(5); i < n; i++ {
+		q.Add(randomWord(rng, 3, 10), randomValue(rng))
+	}
+
+	var body []byte
+	switch rng.IntN(4) {
+	case 1:
+		form := ttsig.NewQuery()
+		for i, n := 0, 1+rng.IntN(4); i < n; i++ {
+			form.Add(randomWord(rng, 2, 8), randomValue(rng))
+		}
+		body = []byte(form.String())
+	case 2:
+		body = make([]byte, rng.IntN(2048))
+		for i := range body {
+			body[i] = byte(rng.UintN(256))
+		}
+	case 3:
+		body = []byte(fmt.Sprintf(`{"cursor":%d,"count":%d}`, rng.IntN(1000), 1+rng.IntN(30)))
+	}
+
+	var cookie string
+	if rng.IntN(2) == 0 {
+		cookie = fmt.Sprintf("sessionid=%x; store-idc=%s", rng.Uint64(), randomWord(rng, 4, 8))
+	}
+
+	rnd := make([]byte, 8)
+	for i := range rnd {
+		rnd[i] = byte(rng.UintN(256))
+	}
+
+	return vectorInput{
+		Query:            q.String(),
+		Body:             hex.EncodeToString(body),
+		Cookie:           cookie,
+		TimestampMillis:  1_600_000_000_000 + rng.Int64N(300_000_000_000),
+		Rand:             hex.EncodeToString(rnd),
+		Platform:         platform.String(),
+		AppID:            prof.AppID,
+		LicenseID:        prof.LicenseID,
+		DeviceID:         deviceID,
+		VersionName:      versionName,
+		SdkVersionString: prof.SdkVersionString,
+		SdkVersionInt:    prof.SdkVersionInt,
+	}
+}
+
+func randomWord(rng *rand.Rand, min, max int) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz_"
+	b := make([]byte, min+rng.IntN(max-min+1))
+	for i := range b {
+		b[i] = letters[rng.IntN(len(letters))]
+	}
+	return string(b)
+}
+
+// randomValue mixes plain words with characters that need escaping.
+func randomValue(rng *rand.Rand) string {
+	specials := []string{" ", "&", "=", "+", "/", "%", "é", "日本", "~", "*"}
+	var sb strings.Builder
+	for i, n := 0, 1+rng.IntN(4); i < n; i++ {
+		if rng.IntN(3) == 0 {
+			sb.WriteString(specials[rng.IntN(len(specials))])
+		} else {
+			sb.WriteString(randomWord(rng, 1, 6))
+		}
+	}
+	return sb.String()
+}
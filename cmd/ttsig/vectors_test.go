@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// vectorsPath is the committed vector file, generated with
+//
+//	ttsig vectors generate --count 16 --seed 1 --out testdata/vectors.json
+const vectorsPath = "../../testdata/vectors.json"
+
+func TestVectorsCheck(t *testing.T) {
+	if err := runVectorsCheck([]string{vectorsPath}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVectorsGenerate pins the generator: the same seed must keep
+// producing the committed file, or ports checked against it go stale.
+func TestVectorsGenerate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "vectors.json")
+	if err := runVectorsGenerate([]string{"--count", "16", "--seed", "1", "--out", out}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(vectorsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generate --seed 1 no longer reproduces %s", vectorsPath)
+	}
+}
+
+func TestVectorsCheckMismatch(t *testing.T) {
+	var file vectorFile
+	if err := readJSONFile(vectorsPath, &file); err != nil {
+		t.Fatal(err)
+	}
+	file.Vectors[3].Intermediate.ArgusSimon = strings.Repeat("00", 16)
+	file.Vectors[5].Headers["x-gorgon"] = "0404b0d30000"
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = runVectorsCheck([]string{path})
+	if err == nil || err.Error() != "2 vectors differ" {
+		t.Errorf("error %v, want 2 vectors differ", err)
+	}
+}
+
+// writeResults writes a results file for check and returns its path.
+func writeResults(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVectorsCheckEmptyResults(t *testing.T) {
+	for _, data := range []string{`{}`, `{"version": 1, "vectors": []}`} {
+		if err := runVectorsCheck([]string{writeResults(t, data)}); err == nil {
+			t.Errorf("%s passed the check", data)
+		}
+	}
+}
+
+// TestVectorsCheckOmitted passes the inputs back with nothing computed:
+// the missing headers fail every vector, while an intermediate counts
+// only when its key is present.
+func TestVectorsCheckOmitted(t *testing.T) {
+	var file vectorFile
+	if err := readJSONFile(vectorsPath, &file); err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]any{"vectors": []map[string]any{
+		{"input": file.Vectors[0].Input},
+		{"input": file.Vectors[1].Input, "headers": file.Vectors[1].Headers},
+		{"input": file.Vectors[2].Input, "headers": file.Vectors[2].Headers,
+			"intermediate": map[string]string{"argus_simon_hex": ""}},
+	}}
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCommand(t, runVectorsCheck, "", writeResults(t, string(data)))
+	if err == nil || err.Error() != "2 vectors differ" {
+		t.Errorf("error %v, want 2 vectors differ", err)
+	}
+	for _, want := range []string{
+		"vector 0: first mismatch at content-length: want 202, missing",
+		"also x-argus: want ",
+		"vector 2: first mismatch at argus_simon_hex: want ",
+		"1/3 vectors match",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "vector 1:") {
+		t.Errorf("vector 1 left its intermediates out and still failed:\n%s", out)
+	}
+}
@@ -0,0 +1,124 @@
+package ttsig
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// HeaderMatch is the result of comparing one signature header against the
+// value we would have produced.
+type HeaderMatch struct {
+	Name string
+	OK   bool
+	// Decoded is set when the comparison was made on decrypted plaintext
+	// because the header embeds random bytes.
+	Decoded bool
+	Detail  string
+}
+
+// CompareHeaders checks every header in want against got, and reports
+// signature headers that only got has. x-ladon and x-argus carry random
+// bytes, so when they differ byte for byte their decrypted plaintext is
+// compared instead, ignoring the Argus random field. aid is needed to
+// derive the x-ladon key.
+func CompareHeaders(want, got SignedHeaders, aid int) []HeaderMatch {
+	return CompareHeadersWithKeys(nil, want, got, aid)
+}
+
+// CompareHeadersWithKeys is CompareHeaders decrypting with keys, which
+// must be the KeySet want was signed with.
+func CompareHeadersWithKeys(keys *KeySet, want, got SignedHeaders, aid int) []HeaderMatch {
+	names := make([]string, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	// Content-Type is only produced for typed bodies; a captured one may
+	// describe a raw body and is not part of the signature.
+	for _, name := range SignatureHeaders() {
+		if _, ok := want[name]; !ok && got[name] != "" && name != "content-type" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := make([]HeaderMatch, 0, len(names))
+	for _, name := range names {
+		out = append(out, compareHeader(keys, name, want[name], got[name], int64(aid)))
+	}
+	return out
+}
+
+func compareHeader(keys *KeySet, name, want, got string, aid int64) HeaderMatch {
+	m := HeaderMatch{Name: name}
+	switch {
+	case want == "":
+		m.Detail = fmt.Sprintf("unexpected %q", got)
+		return m
+	case got == "":
+		m.Detail = "missing"
+		return m
+	case got == want:
+		m.OK = true
+		return m
+	}
+
+	switch name {
+	case "x-ladon":
+		m.Decoded = true
+		w, err := signer.DecodeLadonWithKeys(keys, want, aid)
+		if err != nil {
+			m.Detail = err.Error()
+			return m
+		}
+		g, err := signer.DecodeLadonWithKeys(keys, got, aid)
+		if err != nil {
+			m.Detail = err.Error()
+			return m
+		}
+		if g.Plaintext != w.Plaintext {
+			m.Detail = fmt.Sprintf("plaintext %q, want %q", g.Plaintext, w.Plaintext)
+			return m
+		}
+		m.OK = true
+
+	case "x-argus":
+		m.Decoded = true
+		w, err := signer.DecryptWithKeys(keys, want)
+		if err != nil {
+			m.Detail = err.Error()
+			return m
+		}
+		g, err := signer.DecryptWithKeys(keys, got)
+		if err != nil {
+			m.Detail = err.Error()
+			return m
+		}
+		for _, wf := range w.Fields {
+			if wf.Idx == 3 { // random
+				continue
+			}
+			gf := g.Get(wf.Idx)
+			if gf == nil {
+				m.Detail = fmt.Sprintf("field %d missing", wf.Idx)
+				return m
+			}
+			if gf.String() != wf.String() {
+				m.Detail = fmt.Sprintf("field %s, want %s", gf, wf)
+				return m
+			}
+		}
+		for _, gf := range g.Fields {
+			if gf.Idx != 3 && w.Get(gf.Idx) == nil {
+				m.Detail = fmt.Sprintf("unexpected field %s", gf)
+				return m
+			}
+		}
+		m.OK = true
+
+	default:
+		m.Detail = fmt.Sprintf("got %q, want %q", got, want)
+	}
+	return m
+}
@@ -0,0 +1,105 @@
+package ttsig
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func compareConfig(keys *KeySet, random byte) SignConfig {
+	return SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1233",
+		RequestPayload:       "user_id=1&type=1",
+		Timestamp:            time.UnixMilli(1700000000123),
+		Rand:                 bytes.NewReader([]byte{random, 2, 3, 4, random, 6, 7, 8}),
+		Keys:                 keys,
+	}
+}
+
+func matches(ms []HeaderMatch) map[string]HeaderMatch {
+	out := map[string]HeaderMatch{}
+	for _, m := range ms {
+		out[m.Name] = m
+	}
+	return out
+}
+
+func TestCompareHeaders(t *testing.T) {
+	want, err := SignRequest(compareConfig(nil, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other random bytes: x-ladon and x-argus only match once decrypted.
+	got, err := SignRequest(compareConfig(nil, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range CompareHeaders(want, got, 1233) {
+		if !m.OK {
+			t.Errorf("%s: %s", m.Name, m.Detail)
+		}
+		if m.Decoded != (m.Name == "x-ladon" || m.Name == "x-argus") {
+			t.Errorf("%s: Decoded %v", m.Name, m.Decoded)
+		}
+	}
+
+	delete(got, "x-gorgon")
+	got["x-ss-stub"] = "00"
+	ms := matches(CompareHeaders(want, got, 1233))
+	if m := ms["x-gorgon"]; m.OK || m.Detail != "missing" {
+		t.Errorf("x-gorgon: %+v", m)
+	}
+	if m := ms["x-ss-stub"]; m.OK {
+		t.Errorf("x-ss-stub: %+v", m)
+	}
+}
+
+func TestCompareHeadersExtra(t *testing.T) {
+	cfg := compareConfig(nil, 1)
+	cfg.RequestPayload = ""
+	want, err := SignRequest(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := SignedHeaders{"x-ss-stub": "4CB023E330553AEAB9EB0AA0048B8225", "content-type": "text/plain", "x-custom": "1"}
+	for name, v := range want {
+		got[name] = v
+	}
+	ms := matches(CompareHeaders(want, got, 1233))
+	if m, ok := ms["x-ss-stub"]; !ok || m.OK || m.Detail != `unexpected "4CB023E330553AEAB9EB0AA0048B8225"` {
+		t.Errorf("x-ss-stub only in got: %+v", m)
+	}
+	for _, name := range []string{"content-type", "x-custom"} {
+		if _, ok := ms[name]; ok {
+			t.Errorf("%s is not a signature header but was compared", name)
+		}
+	}
+}
+
+func TestCompareHeadersWithKeys(t *testing.T) {
+	keys := DefaultKeySet()
+	for i := range keys.ArgusSignKey {
+		keys.ArgusSignKey[i] ^= 0x5a
+	}
+	keys.LadonRounds = 0x10
+
+	want, err := SignRequest(compareConfig(keys, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := SignRequest(compareConfig(keys, 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range CompareHeadersWithKeys(keys, want, got, 1233) {
+		if !m.OK {
+			t.Errorf("%s: %s", m.Name, m.Detail)
+		}
+	}
+	ms := matches(CompareHeaders(want, got, 1233))
+	if ms["x-argus"].OK || ms["x-ladon"].OK {
+		t.Error("headers signed with other keys matched under the default keys")
+	}
+}
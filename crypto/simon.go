@@ -35,19 +35,32 @@ func keyExpansion(key []uint64) {
 	}
 }
 
-// SimonDec is the Go equivalent of simon_dec.
-// ct: [2]uint64 ciphertext block
-// k:  [4]uint64 key words
-// c:  mode flag (0 or 1) as in the Python version
-func SimonDec(ct [2]uint64, k [4]uint64, c int) [2]uint64 {
-	var key [72]uint64
+// SimonKey is an expanded SIMON key. SimonEnc and SimonDec expand the key
+// for every block; expanding it once and reusing it skips that work.
+type SimonKey [72]uint64
+
+// ExpandSimonKey runs the key expansion for k.
+func ExpandSimonKey(k [4]uint64) *SimonKey {
+	var key SimonKey
 	key[0] = k[0]
 	key[1] = k[1]
 	key[2] = k[2]
 	key[3] = k[3]
 
 	keyExpansion(key[:])
+	return &key
+}
 
+// SimonDec is the Go equivalent of simon_dec.
+// ct: [2]uint64 ciphertext block
+// k:  [4]uint64 key words
+// c:  mode flag (0 or 1) as in the Python version
+func SimonDec(ct [2]uint64, k [4]uint64, c int) [2]uint64 {
+	return ExpandSimonKey(k).Decrypt(ct, c)
+}
+
+// Decrypt decrypts one block with the expanded key; c is as in SimonDec.
+func (key *SimonKey) Decrypt(ct [2]uint64, c int) [2]uint64 {
 	x := ct[0]
 	y := ct[1]
 
@@ -73,14 +86,11 @@ func SimonDec(ct [2]uint64, k [4]uint64, c int) [2]uint64 {
 // k:  [4]uint64 key words
 // c:  mode flag (0 or 1) as in the Python version
 func SimonEnc(pt [2]uint64, k [4]uint64, c int) [2]uint64 {
-	var key [72]uint64
-	key[0] = k[0]
-	key[1] = k[1]
-	key[2] = k[2]
-	key[3] = k[3]
-
-	keyExpansion(key[:])
+	return ExpandSimonKey(k).Encrypt(pt, c)
+}
 
+// Encrypt encrypts one block with the expanded key; c is as in SimonEnc.
+func (key *SimonKey) Encrypt(pt [2]uint64, c int) [2]uint64 {
 	x := pt[0]
 	y := pt[1]
 
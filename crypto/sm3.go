@@ -3,11 +3,32 @@
 
 package crypto
 
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// Size and BlockSize are the SM3 digest and block lengths in bytes.
+const (
+	Size      = 32
+	BlockSize = 64
+)
+
+// SM3 computes SM3 digests, either in one go with Hash or incrementally
+// through the hash.Hash methods.
 type SM3 struct {
 	iv [8]uint32
 	tj [64]uint32
+
+	// Streaming state, used by Write and Sum only.
+	v   [8]uint32
+	x   [BlockSize]byte
+	nx  int
+	len uint64
 }
 
+var _ hash.Hash = (*SM3)(nil)
+
 //	func New() *SM3 {
 //		return &SM3{
 //			iv: [8]uint32{
@@ -35,6 +56,7 @@ func New() *SM3 {
 		}
 	}
 
+	s.Reset()
 	return s
 }
 
@@ -181,3 +203,62 @@ func (s *SM3) sm3Hash(msg []byte) []byte {
 func (s *SM3) Hash(msg []byte) []byte {
 	return s.sm3Hash(msg)
 }
+
+// Reset discards the data written so far.
+func (s *SM3) Reset() {
+	s.v = s.iv
+	s.nx = 0
+	s.len = 0
+}
+
+// Size returns the digest length, 32.
+func (s *SM3) Size() int { return Size }
+
+// BlockSize returns the block length, 64.
+func (s *SM3) BlockSize() int { return BlockSize }
+
+// Write adds p to the running hash. It never returns an error.
+func (s *SM3) Write(p []byte) (int, error) {
+	n := len(p)
+	s.len += uint64(n)
+
+	if s.nx > 0 {
+		c := copy(s.x[s.nx:], p)
+		s.nx += c
+		p = p[c:]
+		if s.nx < BlockSize {
+			return n, nil
+		}
+		s.v = s.cf(s.v, s.x[:])
+		s.nx = 0
+	}
+	for len(p) >= BlockSize {
+		s.v = s.cf(s.v, p[:BlockSize])
+		p = p[BlockSize:]
+	}
+	s.nx = copy(s.x[:], p)
+	return n, nil
+}
+
+// Sum appends the digest of the data written so far to b. It does not
+// change the running hash, so more data may be written afterwards.
+func (s *SM3) Sum(b []byte) []byte {
+	d := *s
+
+	// Pad with 0x80, zeros up to 56 mod 64 and the 64-bit bit length.
+	var pad [BlockSize + 8]byte
+	pad[0] = 0x80
+	bitLen := d.len * 8
+	n := 56 - int(d.len%BlockSize)
+	if n <= 0 {
+		n += BlockSize
+	}
+	binary.BigEndian.PutUint64(pad[n:], bitLen)
+	d.Write(pad[:n+8])
+
+	var out [Size]byte
+	for i, v := range d.v {
+		binary.BigEndian.PutUint32(out[i*4:], v)
+	}
+	return append(b, out[:]...)
+}
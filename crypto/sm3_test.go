@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzSM3Streaming writes data in chunks of the given size, taking a Sum
+// after each, and checks the final digest matches Hash over all of it.
+func FuzzSM3Streaming(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte, chunk uint8) {
+		want := New().Hash(data)
+
+		size := max(int(chunk), 1)
+		h := New()
+		for rest := data; len(rest) > 0; {
+			n := min(size, len(rest))
+			h.Write(rest[:n])
+			rest = rest[n:]
+			h.Sum(nil)
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Fatalf("streamed in chunks of %d:\ngot  %x\nwant %x", size, got, want)
+		}
+
+		h.Reset()
+		h.Write(data)
+		if got := h.Sum([]byte("prefix")); !bytes.Equal(got[6:], want) || string(got[:6]) != "prefix" {
+			t.Errorf("after Reset: got %x, want prefix%x", got, want)
+		}
+	})
+}
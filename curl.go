@@ -0,0 +1,520 @@
+package ttsig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseCurl parses a curl command line, as copied from a browser or an
+// intercepting proxy, into a request. The leading "curl" is optional and
+// the line is split with POSIX shell quoting, including $'...' strings and
+// backslash-newline continuations.
+//
+// Understood options are -X, -H, -d/--data/--data-raw/--data-binary/
+// --data-ascii, --data-urlencode, -b, -G, -I, -A, -e, -u and --url. Options
+// that only affect how curl runs (-s, -k, -L, --compressed, ...) are
+// ignored; anything else is an error, since its argument count is unknown.
+// Bodies and cookies read from files (@file) are not supported.
+func ParseCurl(cmd string) (*http.Request, error) {
+	args, err := splitShellWords(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCurlArgs(args)
+}
+
+// curlIgnored lists curl options that do not change the request, mapped
+// to whether they take an argument.
+var curlIgnored = map[string]bool{
+	"--compressed": false, "-k": false, "--insecure": false, "-s": false,
+	"--silent": false, "-S": false, "--show-error": false, "-L": false,
+	"--location": false, "-i": false, "--include": false, "-v": false,
+	"--verbose": false, "-g": false, "--globoff": false, "--http1.1": false,
+	"--http2": false, "--http2-prior-knowledge": false, "-f": false,
+	"--fail": false, "-N": false, "--no-buffer": false,
+	"-o": true, "--output": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "--retry": true, "-x": true, "--proxy": true,
+}
+
+// curlArgOptions are the short options that take an argument, so "-XPOST"
+// can be told apart from "-sSL".
+const curlArgOptions = "XHdbAeuxom"
+
+// ParseCurlArgs is ParseCurl for a command line that is already split
+// into words.
+func ParseCurlArgs(args []string) (*http.Request, error) {
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+	// Options are rewritten in place below.
+	args = append([]string(nil), args...)
+
+	var (
+		method  string
+		rawURL  string
+		header  = http.Header{}
+		host    string
+		data    []string
+		hasData bool
+		get     bool
+		cookies []string
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl: option %s needs an argument", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if rawURL != "" {
+				return nil, fmt.Errorf("curl: more than one URL (%q and %q)", rawURL, arg)
+			}
+			rawURL = arg
+			continue
+		}
+
+		// Split "-XPOST" into "-X POST" and "-sSL" into "-s -SL". Long
+		// options are left whole: curl has no "--name=value" form. Values
+		// are never split, as value consumes the next word as is.
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			var rest string
+			if strings.IndexByte(curlArgOptions, arg[1]) >= 0 {
+				arg, rest = arg[:2], arg[2:]
+			} else {
+				arg, rest = arg[:2], "-"+arg[2:]
+			}
+			args = append(args[:i+1], append([]string{rest}, args[i+1:]...)...)
+		}
+
+		switch arg {
+		case "-X", "--request":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			method = v
+
+		case "-H", "--header":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			name, val, ok := strings.Cut(v, ":")
+			if !ok {
+				return nil, fmt.Errorf("curl: malformed header %q", v)
+			}
+			name, val = strings.TrimSpace(name), strings.TrimSpace(val)
+			switch {
+			case strings.EqualFold(name, "Host"):
+				host = val
+			case strings.EqualFold(name, "Cookie"):
+				cookies = append(cookies, val)
+			case val == "" && !strings.HasSuffix(v, ";"):
+				// "-H Name:" removes a header in curl.
+				header.Del(name)
+			default:
+				header.Add(name, val)
+			}
+
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if arg != "--data-raw" && strings.HasPrefix(v, "@") {
+				return nil, fmt.Errorf("curl: reading %s from a file (%s) is not supported", arg, v)
+			}
+			data = append(data, v)
+			hasData = true
+
+		case "--data-urlencode":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if strings.Contains(v, "@") && !strings.Contains(v, "=") {
+				return nil, fmt.Errorf("curl: reading %s from a file (%s) is not supported", arg, v)
+			}
+			if name, content, ok := strings.Cut(v, "="); ok {
+				v = escapeComponent(content)
+				if name != "" {
+					v = name + "=" + v
+				}
+			} else {
+				v = escapeComponent(v)
+			}
+			data = append(data, v)
+			hasData = true
+
+		case "-b", "--cookie":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if !strings.Contains(v, "=") {
+				return nil, fmt.Errorf("curl: reading cookies from a file (%s) is not supported", v)
+			}
+			cookies = append(cookies, v)
+
+		case "-A", "--user-agent":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			header.Set("User-Agent", v)
+
+		case "-e", "--referer":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			header.Set("Referer", v)
+
+		case "-u", "--user":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			user, pass, _ := strings.Cut(v, ":")
+			r := http.Request{Header: http.Header{}}
+			r.SetBasicAuth(user, pass)
+			header.Set("Authorization", r.Header.Get("Authorization"))
+
+		case "--url":
+			v, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if rawURL != "" {
+				return nil, fmt.Errorf("curl: more than one URL (%q and %q)", rawURL, v)
+			}
+			rawURL = v
+
+		case "-G", "--get":
+			get = true
+
+		case "-I", "--head":
+			method = http.MethodHead
+
+		default:
+			takesArg, ok := curlIgnored[arg]
+			if !ok {
+				return nil, fmt.Errorf("curl: unsupported option %s", arg)
+			}
+			if takesArg {
+				if _, err := value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if rawURL == "" {
+		return nil, errors.New("curl: no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("curl: %w", err)
+	}
+
+	body := []byte(strings.Join(data, "&"))
+	if get && hasData {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += string(body)
+		body, hasData = nil, false
+	}
+
+	if method == "" {
+		method = http.MethodGet
+		if hasData {
+			method = http.MethodPost
+		}
+	}
+	if hasData && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", ContentTypeForm)
+	}
+	// The transport computes Content-Length from the body.
+	header.Del("Content-Length")
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("curl: %w", err)
+	}
+	req.Header = header
+	req.Host = host
+	setBody(req, body)
+	return req, nil
+}
+
+// splitShellWords splits s the way a POSIX shell would, without expanding
+// variables: single and double quotes, $'...' escapes, backslashes and
+// line continuations.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+	flush := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, errors.New("curl: trailing backslash")
+			}
+			i++
+			if s[i] == '\n' {
+				continue // line continuation
+			}
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+				continue
+			}
+			word.WriteByte(s[i])
+			inWord = true
+
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("curl: unterminated single quote")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			n, err := readANSIQuoted(s[i+2:], &word)
+			if err != nil {
+				return nil, err
+			}
+			i += 2 + n
+			inWord = true
+
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("curl: unterminated double quote")
+			}
+			inWord = true
+
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
+
+// readANSIQuoted decodes the body of a $'...' string into w and returns
+// the number of bytes consumed, closing quote included.
+func readANSIQuoted(s string, w *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\'' {
+			return i + 1, nil
+		}
+		if c != '\\' {
+			w.WriteByte(c)
+			continue
+		}
+		if i+1 >= len(s) {
+			break
+		}
+		i++
+		switch e := s[i]; e {
+		case 'a':
+			w.WriteByte('\a')
+		case 'b':
+			w.WriteByte('\b')
+		case 'e', 'E':
+			w.WriteByte(0x1b)
+		case 'f':
+			w.WriteByte('\f')
+		case 'n':
+			w.WriteByte('\n')
+		case 'r':
+			w.WriteByte('\r')
+		case 't':
+			w.WriteByte('\t')
+		case 'v':
+			w.WriteByte('\v')
+		case 'x', 'u', 'U':
+			max := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+			n := 0
+			for n < max && i+1+n < len(s) && isHex(s[i+1+n]) {
+				n++
+			}
+			if n == 0 {
+				w.WriteByte('\\')
+				w.WriteByte(e)
+				continue
+			}
+			v, _ := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if e == 'x' {
+				w.WriteByte(byte(v))
+			} else {
+				w.WriteRune(rune(v))
+			}
+			i += n
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n := 1
+			for n < 3 && i+n < len(s) && s[i+n] >= '0' && s[i+n] <= '7' {
+				n++
+			}
+			v, _ := strconv.ParseUint(s[i:i+n], 8, 16)
+			w.WriteByte(byte(v))
+			i += n - 1
+		case '\\', '\'', '"', '?':
+			w.WriteByte(e)
+		default:
+			w.WriteByte('\\')
+			w.WriteByte(e)
+		}
+	}
+	return 0, errors.New("curl: unterminated $'...' string")
+}
+
+// escapeComponent percent-encodes s as --data-urlencode does, keeping
+// only RFC 3986 unreserved characters.
+func escapeComponent(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// FormatCurl renders req as a curl command line that sends the same
+// method, URL, headers and body. Headers are sorted by name. The body is
+// read through req.GetBody when set, leaving req.Body untouched.
+func FormatCurl(req *http.Request) (string, error) {
+	var body []byte
+	switch {
+	case req.GetBody != nil:
+		rc, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return "", err
+		}
+	case req.Body != nil && req.Body != http.NoBody:
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		setBody(req, body)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("curl")
+	if req.Method != "" && req.Method != http.MethodGet {
+		sb.WriteString(" -X " + shellQuote(req.Method))
+	}
+	sb.WriteString(" " + shellQuote(req.URL.String()))
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		sb.WriteString(" \\\n  -H " + shellQuote("Host: "+req.Host))
+	}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range req.Header[name] {
+			sb.WriteString(" \\\n  -H " + shellQuote(name+": "+v))
+		}
+	}
+	if len(body) > 0 {
+		sb.WriteString(" \\\n  --data-binary " + shellQuote(string(body)))
+	}
+	return sb.String(), nil
+}
+
+// shellQuote quotes s for a POSIX shell, using $'...' when s holds bytes
+// that cannot appear literally inside single quotes.
+func shellQuote(s string) string {
+	plain := utf8.ValidString(s)
+	for i := 0; plain && i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			plain = false
+		}
+	}
+	if plain {
+		if s != "" && strings.IndexFunc(s, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+		}) < 0 {
+			return s
+		}
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('\'')
+	return buf.String()
+}
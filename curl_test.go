@@ -0,0 +1,141 @@
+package ttsig
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	tests := []struct {
+		cmd    string
+		method string
+		url    string
+		header map[string]string // a subset of the parsed headers
+		body   string
+	}{
+		{
+			cmd:    `curl 'https://api.test/a?x=1'`,
+			method: "GET", url: "https://api.test/a?x=1",
+		},
+		{
+			cmd:    `curl -X PUT https://api.test/a -H 'X-Foo:  bar ' -d a=1 -d b=2`,
+			method: "PUT", url: "https://api.test/a",
+			header: map[string]string{"X-Foo": "bar", "Content-Type": ContentTypeForm},
+			body:   "a=1&b=2",
+		},
+		{
+			cmd:    `curl -XDELETE -sSL https://api.test/a`,
+			method: "DELETE", url: "https://api.test/a",
+		},
+		{
+			// --data-raw takes a leading @ literally.
+			cmd:    `curl https://api.test/a --data-raw '@user' -H 'Content-Type: text/plain'`,
+			method: "POST", url: "https://api.test/a",
+			header: map[string]string{"Content-Type": "text/plain"},
+			body:   "@user",
+		},
+		{
+			cmd:    `curl https://api.test/a --data-binary $'a\x00b\n\'c\'é'`,
+			method: "POST", url: "https://api.test/a",
+			body: "a\x00b\n'c'é",
+		},
+		{
+			cmd:    `curl https://api.test/a -b 'sessionid=abc' -H 'Cookie: store-idc=x'`,
+			method: "GET", url: "https://api.test/a",
+			header: map[string]string{"Cookie": "sessionid=abc; store-idc=x"},
+		},
+		{
+			cmd:    `curl "https://api.test/a" -H "X-Quote: say \"hi\" \$HOME \\ \n"`,
+			method: "GET", url: "https://api.test/a",
+			header: map[string]string{"X-Quote": `say "hi" $HOME \ \n`},
+		},
+		{
+			cmd:    "curl 'https://api.test/a' \\\n  -H 'X-A: 1' \\\r\n  --compressed \\\n  -H \"X-B: 2\"",
+			method: "GET", url: "https://api.test/a",
+			header: map[string]string{"X-A": "1", "X-B": "2"},
+		},
+		{
+			cmd:    `curl -G https://api.test/a?x=1 -d y=2`,
+			method: "GET", url: "https://api.test/a?x=1&y=2",
+		},
+	}
+	for _, tt := range tests {
+		req, err := ParseCurl(tt.cmd)
+		if err != nil {
+			t.Errorf("%s: %v", tt.cmd, err)
+			continue
+		}
+		if req.Method != tt.method || req.URL.String() != tt.url {
+			t.Errorf("%s: %s %s, want %s %s", tt.cmd, req.Method, req.URL, tt.method, tt.url)
+		}
+		for name, want := range tt.header {
+			if got := req.Header.Get(name); got != want {
+				t.Errorf("%s: %s %q, want %q", tt.cmd, name, got, want)
+			}
+		}
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != tt.body {
+			t.Errorf("%s: body %q, want %q", tt.cmd, body, tt.body)
+		}
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	for _, cmd := range []string{
+		`curl 'https://api.test/a`,
+		`curl "https://api.test/a`,
+		`curl "https://api.test/a\"`,
+		`curl https://api.test/a -H $'X-A: 1`,
+		`curl https://api.test/a \`,
+		`curl https://api.test/a -H`,
+		`curl https://api.test/a --data=a=1`,
+		`curl https://api.test/a -d @body.txt`,
+		`curl https://api.test/a -b cookies.txt`,
+		`curl -H 'X-A: 1'`,
+	} {
+		if req, err := ParseCurl(cmd); err == nil {
+			t.Errorf("%s: parsed as %s %s", cmd, req.Method, req.URL)
+		}
+	}
+}
+
+// TestFormatCurlRoundTrip parses FormatCurl's output back into the same
+// request, including bytes that need $'...' quoting.
+func TestFormatCurlRoundTrip(t *testing.T) {
+	body := "a=1&b='quoted' \"double\"\n\x00\xff$HOME"
+	req, err := http.NewRequest("PATCH", "https://api.test/aweme/v1/feed/?device_id=1&q=it%27s", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "api16.test"
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Quote", `it's "fine" \ $x`)
+	req.Header.Add("X-Multi", "1")
+	req.Header.Add("X-Multi", "2")
+
+	cmd, err := FormatCurl(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseCurl(cmd)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, cmd)
+	}
+	if got.Method != req.Method || got.URL.String() != req.URL.String() || got.Host != req.Host {
+		t.Errorf("%s %s host %s, want %s %s host %s", got.Method, got.URL, got.Host, req.Method, req.URL, req.Host)
+	}
+	if !reflect.DeepEqual(got.Header, req.Header) {
+		t.Errorf("headers %v, want %v", got.Header, req.Header)
+	}
+	gotBody, _ := io.ReadAll(got.Body)
+	if string(gotBody) != body {
+		t.Errorf("body %q, want %q", gotBody, body)
+	}
+	// FormatCurl left the original body readable.
+	if again, _ := io.ReadAll(req.Body); string(again) != body {
+		t.Errorf("original body %q after FormatCurl", again)
+	}
+}
@@ -0,0 +1,133 @@
+package ttsig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// Content types set by the body builders.
+const (
+	ContentTypeForm = "application/x-www-form-urlencoded"
+)
+
+// NewFormBody returns an application/x-www-form-urlencoded body holding
+// fields in order, escaped exactly as Query.String escapes a query string.
+func NewFormBody(fields *Query) *Body {
+	b := NewBody([]byte(fields.String()))
+	b.contentType = ContentTypeForm
+	return b
+}
+
+// Multipart builds a multipart/form-data body. Parts are written in the
+// order they are added; Body encodes them once, so the boundary, CRLFs and
+// closing delimiter that are signed are the ones that are sent.
+type Multipart struct {
+	boundary string
+	parts    []multipartPart
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	src    io.Reader // nil once read into data
+	data   []byte
+}
+
+// NewMultipart returns an empty builder with a random boundary.
+func NewMultipart() *Multipart {
+	return &Multipart{boundary: multipart.NewWriter(io.Discard).Boundary()}
+}
+
+// SetBoundary replaces the random boundary, e.g. to reproduce a capture.
+func (m *Multipart) SetBoundary(boundary string) error {
+	// Validated the same way the encoder will validate it.
+	if err := multipart.NewWriter(io.Discard).SetBoundary(boundary); err != nil {
+		return fmt.Errorf("multipart: %w", err)
+	}
+	m.boundary = boundary
+	return nil
+}
+
+// Boundary returns the boundary in use.
+func (m *Multipart) Boundary() string {
+	return m.boundary
+}
+
+// ContentType returns the Content-Type header value, boundary included.
+func (m *Multipart) ContentType() string {
+	b := m.boundary
+	if strings.ContainsAny(b, `()<>@,;:\"/[]?= `) {
+		b = `"` + b + `"`
+	}
+	return "multipart/form-data; boundary=" + b
+}
+
+// Field adds a plain form field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	return m.Part(h, strings.NewReader(value))
+}
+
+// File adds a file part read from r. contentType defaults to
+// application/octet-stream.
+func (m *Multipart) File(name, filename, contentType string, r io.Reader) *Multipart {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(name), escapeQuotes(filename)))
+	h.Set("Content-Type", contentType)
+	return m.Part(h, r)
+}
+
+// Part adds a part with arbitrary headers.
+func (m *Multipart) Part(header textproto.MIMEHeader, r io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{header: header, src: r})
+	return m
+}
+
+// Body encodes the parts. The readers given to File and Part are read on
+// the first call and their contents kept, so later calls produce the same
+// bytes.
+func (m *Multipart) Body() (*Body, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(m.boundary); err != nil {
+		return nil, fmt.Errorf("multipart: %w", err)
+	}
+	for i := range m.parts {
+		p := &m.parts[i]
+		if p.src != nil {
+			data, err := io.ReadAll(p.src)
+			if err != nil {
+				return nil, fmt.Errorf("multipart: %w", err)
+			}
+			p.src, p.data = nil, data
+		}
+		pw, err := w.CreatePart(p.header)
+		if err != nil {
+			return nil, fmt.Errorf("multipart: %w", err)
+		}
+		if _, err := pw.Write(p.data); err != nil {
+			return nil, fmt.Errorf("multipart: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("multipart: %w", err)
+	}
+
+	b := NewBody(buf.Bytes())
+	b.contentType = m.ContentType()
+	return b, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,189 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormBodyBytes(t *testing.T) {
+	q := NewQuery()
+	q.Add("user_id", "7300000000000000001")
+	q.Add("text", "hi there & bye")
+	q.Add("emoji", "é~")
+	q.Add("empty", "")
+
+	b := NewFormBody(q)
+	got, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "user_id=7300000000000000001&text=hi%20there%20%26%20bye&emoji=%C3%A9~&empty="
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if b.ContentType() != ContentTypeForm {
+		t.Errorf("Content-Type %q", b.ContentType())
+	}
+}
+
+func TestMultipartBytes(t *testing.T) {
+	m := NewMultipart()
+	if err := m.SetBoundary("ttsigboundary"); err != nil {
+		t.Fatal(err)
+	}
+	m.Field("aweme_id", "123")
+	m.Field(`we"ird`, "line1\r\nline2")
+	m.File("file", "a.jpg", "image/jpeg", strings.NewReader("\xff\xd8\xff"))
+	m.File("blob", "b.bin", "", strings.NewReader("x"))
+
+	b, err := m.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--ttsigboundary\r\n" +
+		"Content-Disposition: form-data; name=\"aweme_id\"\r\n" +
+		"\r\n" +
+		"123\r\n" +
+		"--ttsigboundary\r\n" +
+		"Content-Disposition: form-data; name=\"we\\\"ird\"\r\n" +
+		"\r\n" +
+		"line1\r\nline2\r\n" +
+		"--ttsigboundary\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"a.jpg\"\r\n" +
+		"Content-Type: image/jpeg\r\n" +
+		"\r\n" +
+		"\xff\xd8\xff\r\n" +
+		"--ttsigboundary\r\n" +
+		"Content-Disposition: form-data; name=\"blob\"; filename=\"b.bin\"\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"\r\n" +
+		"x\r\n" +
+		"--ttsigboundary--\r\n"
+	if string(got) != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+	if ct := b.ContentType(); ct != "multipart/form-data; boundary=ttsigboundary" {
+		t.Errorf("Content-Type %q", ct)
+	}
+
+	if err := m.SetBoundary("bad boundary\n"); err == nil {
+		t.Error("invalid boundary accepted")
+	}
+	if err := m.SetBoundary("a:b"); err != nil {
+		t.Fatal(err)
+	}
+	if ct := m.ContentType(); ct != `multipart/form-data; boundary="a:b"` {
+		t.Errorf("quoted Content-Type %q", ct)
+	}
+}
+
+func TestMultipartBodyTwice(t *testing.T) {
+	m := NewMultipart()
+	m.Field("aweme_id", "123")
+	m.File("file", "a.jpg", "image/jpeg", strings.NewReader("\xff\xd8\xff"))
+
+	first, err := m.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := first.Bytes()
+	b, _ := second.Bytes()
+	if !bytes.Equal(a, b) || !bytes.Contains(b, []byte("\xff\xd8\xff")) {
+		t.Fatalf("second Body differs:\n%q\n%q", a, b)
+	}
+
+	stub := func(body *Body) string {
+		h, err := SignRequest(SignConfig{
+			RawRequestParameters: "device_id=7300000000000000001&aid=1233",
+			Body:                 body,
+			Timestamp:            time.UnixMilli(1700000000123),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h["x-ss-stub"]
+	}
+	if stub(first) != stub(second) {
+		t.Error("x-ss-stub differs between two Body calls")
+	}
+}
+
+// TestBodyStubMatchesWire sends signed form and multipart bodies and checks
+// that x-ss-stub and Content-Length describe the bytes the server receives.
+func TestBodyStubMatchesWire(t *testing.T) {
+	type received struct {
+		body   []byte
+		header http.Header
+	}
+	got := make(chan received, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{body, r.Header}
+	}))
+	defer ts.Close()
+
+	q := NewQuery()
+	q.Add("user_id", "1")
+	q.Add("text", "a b")
+	m := NewMultipart().Field("aweme_id", "123").File("file", "a.jpg", "image/jpeg", strings.NewReader("jpeg"))
+	mb, err := m.Body()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, body := range map[string]*Body{"form": NewFormBody(q), "multipart": mb} {
+		t.Run(name, func(t *testing.T) {
+			url := ts.URL + "/aweme/v1/upload/?device_id=7300000000000000001&aid=1233"
+			req, err := NewRequest(context.Background(), http.MethodPost, url, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers, err := SignRequest(SignConfig{
+				RawRequestParameters: req.URL.RawQuery,
+				Body:                 body,
+				Timestamp:            time.UnixMilli(1700000000123),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range headers {
+				if k != "content-length" {
+					req.Header.Set(k, v)
+				}
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			r := <-got
+			sum := md5.Sum(r.body)
+			if stub := strings.ToUpper(hex.EncodeToString(sum[:])); stub != headers["x-ss-stub"] || stub != r.header.Get("x-ss-stub") {
+				t.Errorf("x-ss-stub %s, md5 of the received body %s", headers["x-ss-stub"], stub)
+			}
+			if n := r.header.Get("Content-Length"); n != headers["content-length"] {
+				t.Errorf("Content-Length %s, signed %s", n, headers["content-length"])
+			}
+			if ct := r.header.Get("Content-Type"); ct != body.ContentType() || headers["content-type"] != ct {
+				t.Errorf("Content-Type %q, signed %q", ct, headers["content-type"])
+			}
+		})
+	}
+}
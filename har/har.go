@@ -0,0 +1,119 @@
+// Package har reads HTTP Archive captures and re-signs the requests in them,
+// so captured app traffic can be replayed against the signer.
+package har
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// File is the top level of a HAR document. Only the parts the signer needs
+// are modelled.
+type File struct {
+	Log Log `json:"log"`
+}
+
+type Log struct {
+	Entries []Entry `json:"entries"`
+}
+
+type Entry struct {
+	StartedDateTime string  `json:"startedDateTime"`
+	Request         Request `json:"request"`
+}
+
+type Request struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Headers  []NameValue `json:"headers"`
+	Cookies  []NameValue `json:"cookies"`
+	PostData *PostData   `json:"postData,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// Encoding is "base64" when Text holds binary data.
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Parse decodes a HAR document.
+func Parse(r io.Reader) (*File, error) {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("har: %w", err)
+	}
+	return &f, nil
+}
+
+// Load reads and decodes the HAR file at path.
+func Load(path string) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return Parse(fh)
+}
+
+// Header returns the first header with the given name, case-insensitively.
+func (r *Request) Header(name string) string {
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// RawQuery returns the query string exactly as captured.
+func (r *Request) RawQuery() (string, error) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return "", fmt.Errorf("har: %w", err)
+	}
+	return u.RawQuery, nil
+}
+
+// Body returns the request body bytes, decoding base64 captures.
+func (r *Request) Body() ([]byte, error) {
+	if r.PostData == nil {
+		return nil, nil
+	}
+	if r.PostData.Encoding == "base64" {
+		b, err := base64.StdEncoding.DecodeString(r.PostData.Text)
+		if err != nil {
+			return nil, fmt.Errorf("har: postData: %w", err)
+		}
+		return b, nil
+	}
+	return []byte(r.PostData.Text), nil
+}
+
+// Cookie returns the Cookie header, or rebuilds it from the cookie list
+// when the capture tool stripped the header.
+func (r *Request) Cookie() string {
+	if c := r.Header("cookie"); c != "" {
+		return c
+	}
+	parts := make([]string, 0, len(r.Cookies))
+	for _, c := range r.Cookies {
+		parts = append(parts, c.Name+"="+c.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Signed reports whether the request carries signature headers.
+func (r *Request) Signed() bool {
+	return r.Header("x-khronos") != "" && (r.Header("x-argus") != "" || r.Header("x-gorgon") != "")
+}
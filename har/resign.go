@@ -0,0 +1,120 @@
+package har
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Skill/ttsig"
+	"github.com/Skill/ttsig/signer"
+)
+
+// Result is the outcome of re-signing one entry.
+type Result struct {
+	Index   int
+	Method  string
+	URL     string
+	Headers []ttsig.HeaderMatch
+}
+
+// OK reports whether every header matched.
+func (r *Result) OK() bool {
+	for _, h := range r.Headers {
+		if !h.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// SignConfig rebuilds the signing input of a captured request on top of
+// base, which supplies the app constants the capture does not carry. The
+// timestamp comes from the captured x-ss-req-ticket (or x-khronos) and the
+// random bytes of x-ladon and x-argus are replayed so the output is comparable.
+func (r *Request) SignConfig(base ttsig.SignConfig) (ttsig.SignConfig, error) {
+	cfg := base
+
+	query, err := r.RawQuery()
+	if err != nil {
+		return cfg, err
+	}
+	body, err := r.Body()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.RawRequestParameters = query
+	cfg.RequestPayload = ""
+	cfg.Body = ttsig.NewBody(body).WithEncoding(r.Header("content-encoding"))
+	cfg.Cookie = r.Cookie()
+
+	if ticket, err := strconv.ParseInt(r.Header("x-ss-req-ticket"), 10, 64); err == nil {
+		cfg.Timestamp = time.UnixMilli(ticket)
+	} else if khronos, err := strconv.ParseInt(r.Header("x-khronos"), 10, 64); err == nil {
+		cfg.Timestamp = time.Unix(khronos, 0)
+	} else {
+		return cfg, fmt.Errorf("har: request has no x-khronos")
+	}
+
+	// Replay the captured randomness: 4 bytes for x-ladon, then the Argus
+	// random field. Whatever cannot be recovered is left as zeros.
+	random := make([]byte, 8)
+	if ladon := r.Header("x-ladon"); ladon != "" {
+		raw, err := base64.StdEncoding.DecodeString(ladon)
+		if err == nil && len(raw) >= 4 {
+			copy(random[:4], raw[:4])
+		}
+	}
+	if argus := r.Header("x-argus"); argus != "" {
+		if pb, err := signer.DecryptWithKeys(cfg.Keys, argus); err == nil {
+			if v, err := pb.GetInt(3); err == nil {
+				binary.LittleEndian.PutUint32(random[4:], uint32(v))
+			}
+		}
+	}
+	cfg.Rand = bytes.NewReader(random)
+
+	return cfg, nil
+}
+
+// Resign re-signs every signed entry in f and compares the result with
+// the captured headers. Unsigned entries are skipped.
+func Resign(f *File, base ttsig.SignConfig) ([]Result, error) {
+	var results []Result
+	for i := range f.Log.Entries {
+		req := &f.Log.Entries[i].Request
+		if !req.Signed() {
+			continue
+		}
+
+		cfg, err := req.SignConfig(base)
+		if err != nil {
+			return results, fmt.Errorf("entry %d: %w", i, err)
+		}
+		want, err := ttsig.SignRequest(cfg)
+		if err != nil {
+			return results, fmt.Errorf("entry %d: %w", i, err)
+		}
+
+		got := ttsig.SignedHeaders{}
+		for _, name := range ttsig.SignatureHeaders() {
+			if v := req.Header(name); v != "" {
+				got[name] = v
+			}
+		}
+
+		resolved, err := cfg.Resolve()
+		if err != nil {
+			return results, fmt.Errorf("entry %d: %w", i, err)
+		}
+		results = append(results, Result{
+			Index:   i,
+			Method:  req.Method,
+			URL:     req.URL,
+			Headers: ttsig.CompareHeadersWithKeys(resolved.Keys, want, got, resolved.AppID),
+		})
+	}
+	return results, nil
+}
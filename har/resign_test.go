@@ -0,0 +1,94 @@
+package har
+
+import (
+	"testing"
+
+	"github.com/Skill/ttsig"
+)
+
+// testdata/sample.har holds three signed requests (a GET, a form POST with
+// a cookie and a gzip body stored as base64) and one unsigned request. Its
+// signatures were produced by this package's signer at fixed times and
+// random bytes, so re-signing must reproduce every header exactly.
+const sample = "testdata/sample.har"
+
+func TestResignSample(t *testing.T) {
+	f, err := Load(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := Resign(f, ttsig.SignConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("%d results, want 3 (the unsigned entry is skipped)", len(results))
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("result %d has index %d", i, r.Index)
+		}
+		if !r.OK() {
+			for _, h := range r.Headers {
+				if !h.OK {
+					t.Errorf("entry %d %s: %s", r.Index, h.Name, h.Detail)
+				}
+			}
+		}
+	}
+}
+
+func TestResignDetectsTampering(t *testing.T) {
+	tests := []struct {
+		entry  int
+		header string
+		value  string
+	}{
+		{0, "x-gorgon", "0404b0d30000aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		{1, "x-ss-stub", "00000000000000000000000000000000"},
+		{1, "x-khronos", "1700000043"},
+		// A valid header from another request decrypts fine but carries
+		// the wrong plaintext.
+		{2, "x-ladon", ""},
+		{2, "x-argus", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			f, err := Load(sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value := tt.value
+			if value == "" {
+				value = f.Log.Entries[0].Request.Header(tt.header)
+			}
+			setHeader(&f.Log.Entries[tt.entry].Request, tt.header, value)
+
+			results, err := Resign(f, ttsig.SignConfig{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, h := range results[tt.entry].Headers {
+				if h.Name == tt.header {
+					if h.OK {
+						t.Errorf("tampered %s matched", tt.header)
+					}
+					return
+				}
+			}
+			t.Errorf("%s not compared", tt.header)
+		})
+	}
+}
+
+func setHeader(r *Request, name, value string) {
+	for i := range r.Headers {
+		if r.Headers[i].Name == name {
+			r.Headers[i].Value = value
+			return
+		}
+	}
+	r.Headers = append(r.Headers, NameValue{Name: name, Value: value})
+}
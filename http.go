@@ -0,0 +1,108 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SignatureHeaders returns the names of every header SignRequest may
+// produce, in a new slice the caller may modify.
+func SignatureHeaders() []string {
+	return []string{
+		"content-length",
+		"content-type",
+		"x-argus",
+		"x-gorgon",
+		"x-khronos",
+		"x-ladon",
+		"x-ss-req-ticket",
+		"x-ss-stub",
+	}
+}
+
+// SignHTTPRequest signs req in place. The query, body and Cookie header of
+// req replace those in base; base supplies everything else. Existing
+// signature headers are removed before the new ones are set, and the body
+// is read and replaced so req can still be sent.
+func SignHTTPRequest(req *http.Request, base SignConfig) (SignedHeaders, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading body: %w", err)
+		}
+	}
+	setBody(req, body)
+
+	cfg := base
+	cfg.Query = nil
+	cfg.RawRequestParameters = req.URL.RawQuery
+	cfg.RequestPayload = ""
+	cfg.Body = NewBody(body).WithEncoding(req.Header.Get("Content-Encoding"))
+	cfg.Cookie = req.Header.Get("Cookie")
+
+	headers, err := SignRequest(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range SignatureHeaders() {
+		// The Content-Type set by the caller describes the raw body, which
+		// SignRequest reports no type for.
+		if name != "content-type" {
+			req.Header.Del(name)
+		}
+	}
+	for name, value := range headers {
+		// The transport writes Content-Length from req.ContentLength.
+		if name == "content-length" {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	return headers, nil
+}
+
+// NewRequest returns a request carrying body's wire bytes, with
+// Content-Length, Content-Type and Content-Encoding taken from body. Pass
+// the same Body in SignConfig so the bytes signed are the bytes sent.
+func NewRequest(ctx context.Context, method, url string, body *Body) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return req, nil
+	}
+
+	data, err := body.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	setBody(req, data)
+	if ct := body.ContentType(); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	if ce := body.ContentEncoding(); ce != "" {
+		req.Header.Set("Content-Encoding", ce)
+	}
+	return req, nil
+}
+
+func setBody(req *http.Request, body []byte) {
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
@@ -0,0 +1,53 @@
+package ttsig
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignHTTPRequestHeaders(t *testing.T) {
+	req, err := NewRequest(context.Background(), http.MethodPost,
+		"https://api.example.com/aweme/v1/commit/follow/user/?device_id=7300000000000000001&aid=1233",
+		NewBody([]byte("user_id=1&type=1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	req.Header.Set("X-Gorgon", "stale")
+	req.Header.Set("X-Ss-Stub", "stale")
+
+	headers, err := SignHTTPRequest(req, SignConfig{Timestamp: time.UnixMilli(1700000000123)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/x-www-form-urlencoded") {
+		t.Errorf("Content-Type %q was not kept", got)
+	}
+	for _, name := range []string{"x-gorgon", "x-ss-stub"} {
+		if got := req.Header.Values(name); len(got) != 1 || got[0] != headers[name] {
+			t.Errorf("%s = %q, want only %s", name, got, headers[name])
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range SignatureHeaders() {
+		known[name] = true
+	}
+	for name := range headers {
+		if !known[name] {
+			t.Errorf("SignatureHeaders lacks %s", name)
+		}
+	}
+	if !known["content-type"] {
+		t.Error("SignatureHeaders lacks content-type")
+	}
+
+	// The result is a copy.
+	SignatureHeaders()[0] = "changed"
+	if SignatureHeaders()[0] != "content-length" {
+		t.Error("SignatureHeaders shares its slice")
+	}
+}
@@ -0,0 +1,106 @@
+package ttsig
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// Stage names a step of SignRequest reported to an Observer.
+type Stage string
+
+const (
+	StageSign          Stage = "sign" // the whole call
+	StageResolve       Stage = "resolve"
+	StageBody          Stage = "body"
+	StageGorgon        Stage = "gorgon"
+	StageLadon         Stage = "ladon"
+	StageArgus         Stage = "argus"
+	StageArgusProtobuf Stage = "argus_protobuf"
+	StageArgusSimon    Stage = "argus_simon"
+	StageArgusAES      Stage = "argus_aes"
+)
+
+// Observer receives per-stage measurements from SignRequest. It is called
+// synchronously on the signing goroutine, so implementations must be
+// cheap and safe for concurrent use.
+type Observer interface {
+	// ObserveStage is called after every stage that ran, with its duration
+	// and the error it failed with, if any. The Argus sub-stages are only
+	// reported when Argus encoding got that far.
+	ObserveStage(stage Stage, d time.Duration, err error)
+
+	// ObserveBody is called once per signature with the wire size of the
+	// body.
+	ObserveBody(size int64)
+}
+
+// ErrorReason classifies a signing error into a short, stable label
+// suitable for a metric: "query_mismatch", "no_query", "no_device_id",
+// "keyset", "bean", "canceled" or "other".
+func ErrorReason(err error) string {
+	var mismatch *QueryMismatchError
+	var bean *signer.BeanError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &mismatch):
+		return "query_mismatch"
+	case errors.Is(err, errNoQuery):
+		return "no_query"
+	case errors.Is(err, signer.ErrNoDeviceID):
+		return "no_device_id"
+	case errors.As(err, &bean):
+		return "bean"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, signer.ErrInvalidKeySet):
+		return "keyset"
+	}
+	return "other"
+}
+
+// stageTimer reports stages to an Observer; a nil observer makes every
+// method a no-op.
+type stageTimer struct {
+	obs Observer
+}
+
+// start returns a function that reports stage with the time since start.
+func (t stageTimer) start(stage Stage) func(err error) {
+	if t.obs == nil {
+		return func(error) {}
+	}
+	begin := time.Now()
+	return func(err error) {
+		t.obs.ObserveStage(stage, time.Since(begin), err)
+	}
+}
+
+// argusTimings returns where SignTraced records the Argus sub-stages, or
+// nil when nobody is listening so the clock is not read.
+func (t stageTimer) argusTimings() *signer.ArgusTimings {
+	if t.obs == nil {
+		return nil
+	}
+	return new(signer.ArgusTimings)
+}
+
+func (t stageTimer) argus(a *signer.ArgusTimings, err error) {
+	if t.obs == nil {
+		return
+	}
+	t.obs.ObserveStage(StageArgusProtobuf, a.Protobuf, err)
+	if err == nil {
+		t.obs.ObserveStage(StageArgusSimon, a.Simon, nil)
+		t.obs.ObserveStage(StageArgusAES, a.AES, nil)
+	}
+}
+
+func (t stageTimer) body(size int64) {
+	if t.obs != nil {
+		t.obs.ObserveBody(size)
+	}
+}
@@ -0,0 +1,39 @@
+package ttsig
+
+import (
+	"expvar"
+	"time"
+)
+
+// ExpvarObserver publishes signing metrics in an expvar.Map, visible at
+// /debug/vars. For each stage it keeps "<stage>.count", "<stage>.seconds"
+// (total) and "<stage>.errors", plus "errors.<stage>.<reason>"; bodies are
+// counted in "body_size.count" and "body_size.bytes".
+type ExpvarObserver struct {
+	m *expvar.Map
+}
+
+// NewExpvarObserver publishes a new map under name. Like expvar.Publish it
+// panics if the name is already in use.
+func NewExpvarObserver(name string) *ExpvarObserver {
+	return &ExpvarObserver{m: expvar.NewMap(name)}
+}
+
+// Map returns the published map.
+func (o *ExpvarObserver) Map() *expvar.Map {
+	return o.m
+}
+
+func (o *ExpvarObserver) ObserveStage(stage Stage, d time.Duration, err error) {
+	o.m.Add(string(stage)+".count", 1)
+	o.m.AddFloat(string(stage)+".seconds", d.Seconds())
+	if err != nil {
+		o.m.Add(string(stage)+".errors", 1)
+		o.m.Add("errors."+string(stage)+"."+ErrorReason(err), 1)
+	}
+}
+
+func (o *ExpvarObserver) ObserveBody(size int64) {
+	o.m.Add("body_size.count", 1)
+	o.m.Add("body_size.bytes", size)
+}
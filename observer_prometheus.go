@@ -0,0 +1,203 @@
+package ttsig
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Histogram bucket upper bounds used by PrometheusObserver.
+var (
+	stageBuckets = []float64{10e-6, 25e-6, 50e-6, 100e-6, 250e-6, 500e-6, 1e-3, 2.5e-3, 5e-3, 10e-3, 25e-3, 100e-3}
+	bodyBuckets  = []float64{0, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+)
+
+// PrometheusObserver keeps signing metrics in memory and serves them in the
+// Prometheus text exposition format, without the client library:
+//
+//	ttsig_stage_duration_seconds  histogram by stage
+//	ttsig_stage_errors_total      counter by stage and reason
+//	ttsig_body_bytes              histogram of body sizes
+//
+// Mount it as an http.Handler, or append WriteMetrics to an existing
+// /metrics endpoint. Counters registered with NewCounter are written after
+// the signing metrics, so a service can expose its own on the same page.
+type PrometheusObserver struct {
+	mu       sync.Mutex
+	stages   map[Stage]*histogram
+	errors   map[[2]string]uint64 // {stage, reason}
+	body     *histogram
+	counters []*CounterVec
+}
+
+// NewPrometheusObserver returns an empty observer.
+func NewPrometheusObserver() *PrometheusObserver {
+	return &PrometheusObserver{
+		stages: make(map[Stage]*histogram),
+		errors: make(map[[2]string]uint64),
+		body:   newHistogram(bodyBuckets),
+	}
+}
+
+func (o *PrometheusObserver) ObserveStage(stage Stage, d time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	h, ok := o.stages[stage]
+	if !ok {
+		h = newHistogram(stageBuckets)
+		o.stages[stage] = h
+	}
+	h.observe(d.Seconds())
+	if err != nil {
+		o.errors[[2]string{string(stage), ErrorReason(err)}]++
+	}
+}
+
+func (o *PrometheusObserver) ObserveBody(size int64) {
+	o.mu.Lock()
+	o.body.observe(float64(size))
+	o.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics.
+func (o *PrometheusObserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	o.WriteMetrics(w)
+}
+
+// WriteMetrics writes the metrics in the text exposition format.
+func (o *PrometheusObserver) WriteMetrics(w io.Writer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP ttsig_stage_duration_seconds Time spent in each signing stage.")
+	fmt.Fprintln(w, "# TYPE ttsig_stage_duration_seconds histogram")
+	stages := make([]string, 0, len(o.stages))
+	for s := range o.stages {
+		stages = append(stages, string(s))
+	}
+	sort.Strings(stages)
+	for _, s := range stages {
+		o.stages[Stage(s)].write(w, "ttsig_stage_duration_seconds", fmt.Sprintf("stage=%q,", s))
+	}
+
+	fmt.Fprintln(w, "# HELP ttsig_stage_errors_total Failed signing stages by reason.")
+	fmt.Fprintln(w, "# TYPE ttsig_stage_errors_total counter")
+	keys := make([][2]string, 0, len(o.errors))
+	for k := range o.errors {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "ttsig_stage_errors_total{stage=%q,reason=%q} %d\n", k[0], k[1], o.errors[k])
+	}
+
+	fmt.Fprintln(w, "# HELP ttsig_body_bytes Size of signed request bodies.")
+	fmt.Fprintln(w, "# TYPE ttsig_body_bytes histogram")
+	o.body.write(w, "ttsig_body_bytes", "")
+
+	for _, c := range o.counters {
+		c.write(w)
+	}
+}
+
+// CounterVec is a counter family with fixed label names, registered with
+// a PrometheusObserver by NewCounter.
+type CounterVec struct {
+	o      *PrometheusObserver
+	name   string
+	help   string
+	labels []string
+	values map[string]uint64 // label values joined by "\xff"
+}
+
+// NewCounter registers a counter family written by WriteMetrics.
+func (o *PrometheusObserver) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{o: o, name: name, help: help, labels: labels, values: make(map[string]uint64)}
+	o.mu.Lock()
+	o.counters = append(o.counters, c)
+	o.mu.Unlock()
+	return c
+}
+
+// Inc adds one to the series with the given label values, which must be
+// as many as the label names.
+func (c *CounterVec) Inc(values ...string) {
+	if len(values) != len(c.labels) {
+		panic(fmt.Sprintf("ttsig: counter %s takes %d label values, got %d", c.name, len(c.labels), len(values)))
+	}
+	c.o.mu.Lock()
+	c.values[strings.Join(values, "\xff")]++
+	c.o.mu.Unlock()
+}
+
+// write emits the family; callers hold the lock.
+func (c *CounterVec) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", c.name, c.help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", c.name)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var labels strings.Builder
+		if len(c.labels) > 0 {
+			for i, v := range strings.Split(k, "\xff") {
+				fmt.Fprintf(&labels, "%s=%q,", c.labels[i], v)
+			}
+		}
+		fmt.Fprintf(w, "%s%s %d\n", c.name, trimLabels(labels.String()), c.values[k])
+	}
+}
+
+// histogram is a cumulative Prometheus histogram; callers hold the lock.
+type histogram struct {
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative; last is +Inf
+	sum    float64
+	n      uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.n++
+}
+
+// write emits the series of name; labels is either empty or a label list
+// ending in a comma.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	var cum uint64
+	for i, b := range h.bounds {
+		cum += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, labels, strconv.FormatFloat(b, 'g', -1, 64), cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.n)
+	labels = trimLabels(labels)
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, labels, h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.n)
+}
+
+// trimLabels turns `a="b",` into `{a="b"}`, and "" into "".
+func trimLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels[:len(labels)-1] + "}"
+}
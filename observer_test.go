@@ -0,0 +1,108 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// recordingObserver keeps every stage it is told about.
+type recordingObserver struct {
+	mu     sync.Mutex
+	stages []Stage
+	errs   []error
+	bodies []int64
+}
+
+func (o *recordingObserver) ObserveStage(stage Stage, d time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stages = append(o.stages, stage)
+	o.errs = append(o.errs, err)
+}
+
+func (o *recordingObserver) ObserveBody(size int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.bodies = append(o.bodies, size)
+}
+
+func observedConfig() SignConfig {
+	return SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1233",
+		RequestPayload:       "user_id=1&type=1",
+		Timestamp:            time.UnixMilli(1700000000123),
+		Rand:                 bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8}),
+	}
+}
+
+func TestObserverStages(t *testing.T) {
+	want, err := SignRequest(observedConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obs := &recordingObserver{}
+	cfg := observedConfig()
+	cfg.Observer = obs
+	got, err := SignRequest(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("observing changed the headers:\ngot  %v\nwant %v", got, want)
+	}
+
+	stages := []Stage{
+		StageResolve, StageBody, StageGorgon, StageLadon,
+		StageArgusProtobuf, StageArgusSimon, StageArgusAES, StageArgus, StageSign,
+	}
+	if !reflect.DeepEqual(obs.stages, stages) {
+		t.Errorf("stages %v, want %v", obs.stages, stages)
+	}
+	for i, err := range obs.errs {
+		if err != nil {
+			t.Errorf("%s: %v", obs.stages[i], err)
+		}
+	}
+	if len(obs.bodies) != 1 || obs.bodies[0] != int64(len(cfg.RequestPayload)) {
+		t.Errorf("bodies %v, want [%d]", obs.bodies, len(cfg.RequestPayload))
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	badKeys := signer.DefaultKeySet()
+	badKeys.LadonRounds = 0
+	_, keysetErr := SignRequest(SignConfig{RawRequestParameters: "device_id=1&aid=1233", Keys: badKeys})
+	_, mismatchErr := SignRequest(SignConfig{RawRequestParameters: "device_id=1&aid=1233", AppID: 1128})
+	_, noQueryErr := SignRequest(SignConfig{})
+
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{keysetErr, "keyset"},
+		{fmt.Errorf("wrapped: %w", keysetErr), "keyset"},
+		{mismatchErr, "query_mismatch"},
+		{noQueryErr, "no_query"},
+		{signer.ErrNoDeviceID, "no_device_id"},
+		{&signer.BeanError{Field: 5, Value: 1.5, Reason: "unsupported value type"}, "bean"},
+		{context.Canceled, "canceled"},
+		{context.DeadlineExceeded, "canceled"},
+		// Only the sentinel counts, not a message that looks like one.
+		{errors.New("keyset: made up"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorReason(tt.err); got != tt.want {
+			t.Errorf("ErrorReason(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package ttsig
+
+import "github.com/Skill/ttsig/signer"
+
+// Platform selects the app build being signed for; see signer.Platform.
+type Platform = signer.Platform
+
+const (
+	PlatformUnset   = signer.PlatformUnset
+	PlatformAndroid = signer.PlatformAndroid
+)
+
+// ParsePlatform parses "android" or a device_platform query value.
+func ParsePlatform(s string) (Platform, error) {
+	return signer.ParsePlatform(s)
+}
@@ -0,0 +1,99 @@
+package ttsig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPlatformVectors pins the headers of each platform with a built-in
+// profile, so a profile change shows up as a diff here.
+func TestPlatformVectors(t *testing.T) {
+	tests := []struct {
+		platform Platform
+		query    string
+		want     SignedHeaders
+	}{
+		{
+			platform: PlatformAndroid,
+			query:    "device_id=1&aid=1233&x=0",
+			want: SignedHeaders{
+				"content-length":  "3",
+				"x-argus":         "8oHLd2IxRuxrAwq79hCEmcOtpb5id3+/kNtkjkHWqF3lRgreQCnGzWlU0pNEOfbOwHZWEgkjlbLbKxJlOaCgp4i9qM0IbrNU8lNhqC70/n1RkFtfnuam58DCzz7hZ46/gTk41Z6zp6vKyxtarJ6viA6cFHKI8GrSTr0njMY4yx6tEglRFUjTZdEaguEDVx1SFOIBus/2A6Mh0BLlAdcCn2+Y",
+				"x-gorgon":        "0404b0d30000d2e93710fb5581714d7b04173694a6177ca72d9c",
+				"x-khronos":       "1700000000",
+				"x-ladon":         "AQIDBDVFfP9USKdZmCmktvwqxsZDhD8hxvfmokrn6O+AmKsJ",
+				"x-ss-req-ticket": "1700000000123",
+				"x-ss-stub":       "900150983CD24FB0D6963F7D28E17F72",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform.String(), func(t *testing.T) {
+			// The platform set explicitly and the one derived from the
+			// query's device_platform must sign the same.
+			for _, cfg := range []SignConfig{
+				{RawRequestParameters: tt.query, Platform: tt.platform},
+				{RawRequestParameters: tt.query + "&device_platform=" + tt.platform.String()},
+			} {
+				cfg.RequestPayload = "abc"
+				cfg.Cookie = "c=d"
+				cfg.Timestamp = time.UnixMilli(1700000000123)
+				cfg.Rand = bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+				got, err := SignRequest(cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(cfg.RawRequestParameters, "device_platform") {
+					// The query differs, so only the query-independent
+					// headers can be compared.
+					for _, name := range []string{"x-ladon", "x-ss-stub", "x-khronos"} {
+						if got[name] != tt.want[name] {
+							t.Errorf("%s = %s, want %s", name, got[name], tt.want[name])
+						}
+					}
+					continue
+				}
+				for name, want := range tt.want {
+					if got[name] != want {
+						t.Errorf("%s = %s, want %s", name, got[name], want)
+					}
+				}
+				if len(got) != len(tt.want) {
+					t.Errorf("got %d headers, want %d", len(got), len(tt.want))
+				}
+			}
+		})
+	}
+}
+
+// iOS has no captured profile, so iOS requests must fail instead of being
+// signed with the Android constants.
+func TestPlatformIOSRejected(t *testing.T) {
+	for _, dp := range []string{"iphone", "ipad", "ios"} {
+		_, err := SignRequest(SignConfig{RawRequestParameters: "device_id=1&aid=1233&device_platform=" + dp})
+		if err == nil || !strings.Contains(err.Error(), "is not supported") {
+			t.Errorf("%s: error %v", dp, err)
+		}
+	}
+
+	_, err := SignRequest(SignConfig{RawRequestParameters: "device_id=1&aid=1233", Platform: Platform(2)})
+	if err == nil || !strings.Contains(err.Error(), "no profile for Platform(2)") {
+		t.Errorf("Platform(2): error %v", err)
+	}
+	if _, err := New(WithPlatform(Platform(2))); err == nil {
+		t.Error("New accepted a platform without a profile")
+	}
+}
+
+func TestDefaultAppID(t *testing.T) {
+	prof, err := PlatformAndroid.Profile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prof.AppID != DefaultAppID {
+		t.Errorf("DefaultAppID %d, Android profile aid %d", DefaultAppID, prof.AppID)
+	}
+}
@@ -0,0 +1,79 @@
+package ttsig
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// Query is an ordered query string that keeps its exact encoding; see signer.Query.
+type Query = signer.Query
+
+// ParseQuery parses a raw query string without re-encoding it.
+func ParseQuery(raw string) (*Query, error) {
+	return signer.ParseQuery(raw)
+}
+
+// NewQuery returns an empty Query.
+func NewQuery() *Query {
+	return signer.NewQuery()
+}
+
+// QueryMismatchError reports a SignConfig field that contradicts the value
+// the query carries. Signing anyway would produce a signature the server
+// rejects, since it checks the bean against the URL.
+type QueryMismatchError struct {
+	Param  string
+	Config string
+	Query  string
+}
+
+func (e *QueryMismatchError) Error() string {
+	return fmt.Sprintf("ttsig: %s is %q in SignConfig but %q in the query", e.Param, e.Config, e.Query)
+}
+
+// resolveQuery fills AppID, Platform, DeviceID and VersionName from the query where
+// the config leaves them empty, and rejects explicit values that disagree.
+func (c *SignConfig) resolveQuery(q *Query) error {
+	aid, err := q.AID()
+	if err != nil {
+		return err
+	}
+	if aid != 0 {
+		if c.AppID == 0 {
+			c.AppID = aid
+		} else if c.AppID != aid {
+			return &QueryMismatchError{Param: "aid", Config: strconv.Itoa(c.AppID), Query: strconv.Itoa(aid)}
+		}
+	}
+
+	if dp := q.DevicePlatform(); dp != "" {
+		platform, err := ParsePlatform(dp)
+		if err != nil {
+			return err
+		}
+		if c.Platform == PlatformUnset {
+			c.Platform = platform
+		} else if c.Platform != platform {
+			return &QueryMismatchError{Param: "device_platform", Config: c.Platform.String(), Query: dp}
+		}
+	}
+
+	if err := resolveString(&c.DeviceID, q, "device_id"); err != nil {
+		return err
+	}
+	return resolveString(&c.VersionName, q, "version_name")
+}
+
+func resolveString(field *string, q *Query, param string) error {
+	v := q.Get(param)
+	switch {
+	case v == "":
+	case *field == "":
+		*field = v
+	case *field != v:
+		return &QueryMismatchError{Param: param, Config: *field, Query: v}
+	}
+	return nil
+}
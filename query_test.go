@@ -0,0 +1,61 @@
+package ttsig
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQueryMismatch(t *testing.T) {
+	const query = "device_id=7300000000000000001&aid=1233&device_platform=android&version_name=39.6.3"
+
+	tests := []struct {
+		name  string
+		cfg   SignConfig
+		param string
+		want  string
+	}{
+		{"aid", SignConfig{AppID: 1128}, "aid", `ttsig: aid is "1128" in SignConfig but "1233" in the query`},
+		{"device_id", SignConfig{DeviceID: "1"}, "device_id", `ttsig: device_id is "1" in SignConfig but "7300000000000000001" in the query`},
+		{"version_name", SignConfig{VersionName: "1.0.0"}, "version_name", `ttsig: version_name is "1.0.0" in SignConfig but "39.6.3" in the query`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.RawRequestParameters = query
+			_, err := SignRequest(cfg)
+			var mismatch *QueryMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("error %v, want a QueryMismatchError", err)
+			}
+			if mismatch.Param != tt.param {
+				t.Errorf("Param %q, want %q", mismatch.Param, tt.param)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got  %s\nwant %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryFillsConfig(t *testing.T) {
+	cfg, err := SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1128&device_platform=android&version_name=30.1.0",
+	}.Resolve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 1128 || cfg.Platform != PlatformAndroid || cfg.DeviceID != "7300000000000000001" || cfg.VersionName != "30.1.0" {
+		t.Errorf("resolved %d %v %q %q", cfg.AppID, cfg.Platform, cfg.DeviceID, cfg.VersionName)
+	}
+
+	// Values that agree with the query are accepted.
+	_, err = SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1128",
+		AppID:                1128,
+		DeviceID:             "7300000000000000001",
+	}.Resolve()
+	if err != nil {
+		t.Errorf("agreeing config rejected: %v", err)
+	}
+}
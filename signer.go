@@ -0,0 +1,313 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Signer signs requests for one client: it holds the app constants, keys,
+// clock, randomness and observer, and the key schedules derived from the
+// keys, so they are set up once instead of on every call. A Signer is safe
+// for concurrent use.
+type Signer struct {
+	// base holds the app constants and hooks set by the options; the
+	// request fields of a SignConfig are left empty.
+	base      SignConfig
+	schedules *scheduleCache
+
+	randMu sync.Mutex
+	rand   io.Reader
+}
+
+// Option configures a Signer in New.
+type Option func(*Signer) error
+
+// WithPlatform selects the app build. Its profile supplies the app
+// constants that are not set explicitly; aid and version_name still come
+// from each request's query when present.
+func WithPlatform(p Platform) Option {
+	return func(s *Signer) error {
+		if p != PlatformUnset {
+			if _, err := p.Profile(); err != nil {
+				return err
+			}
+		}
+		s.base.Platform = p
+		return nil
+	}
+}
+
+// WithAppID sets the aid.
+func WithAppID(aid int) Option {
+	return func(s *Signer) error {
+		s.base.AppID = aid
+		return nil
+	}
+}
+
+// WithLicenseID sets the license id carried by x-ladon and x-argus.
+func WithLicenseID(id int) Option {
+	return func(s *Signer) error {
+		s.base.LicenseID = id
+		return nil
+	}
+}
+
+// WithSdkVersion sets the SDK version string and number of the Argus bean.
+func WithSdkVersion(version string, number int) Option {
+	return func(s *Signer) error {
+		s.base.SdkVersionString = version
+		s.base.SdkVersionInt = number
+		return nil
+	}
+}
+
+// WithVersionName sets the app version used when a request's query has no
+// version_name.
+func WithVersionName(v string) Option {
+	return func(s *Signer) error {
+		s.base.VersionName = v
+		return nil
+	}
+}
+
+// WithConfig takes the app constants and hooks that are set in cfg, e.g.
+// one loaded from a JSON config file, over those of earlier options; its
+// zero fields leave them alone. Its request fields (query, body, cookie,
+// timestamp, device_id) are ignored.
+func WithConfig(cfg SignConfig) Option {
+	return func(s *Signer) error {
+		b := &s.base
+		if cfg.Platform != PlatformUnset {
+			if err := WithPlatform(cfg.Platform)(s); err != nil {
+				return err
+			}
+		}
+		if cfg.SecDeviceID != "" {
+			b.SecDeviceID = cfg.SecDeviceID
+		}
+		if cfg.AppID != 0 {
+			b.AppID = cfg.AppID
+		}
+		if cfg.LicenseID != 0 {
+			b.LicenseID = cfg.LicenseID
+		}
+		if cfg.SdkVersionString != "" {
+			b.SdkVersionString = cfg.SdkVersionString
+		}
+		if cfg.SdkVersionInt != 0 {
+			b.SdkVersionInt = cfg.SdkVersionInt
+		}
+		if cfg.VersionName != "" {
+			b.VersionName = cfg.VersionName
+		}
+		if cfg.Skew != nil {
+			b.Skew = cfg.Skew
+		}
+		if cfg.Clock != nil {
+			b.Clock = cfg.Clock
+		}
+		if cfg.Keys != nil {
+			b.Keys = cfg.Keys
+		}
+		if cfg.Observer != nil {
+			b.Observer = cfg.Observer
+		}
+		b.Strict = b.Strict || cfg.Strict
+		if cfg.Rand != nil {
+			s.rand = cfg.Rand
+		}
+		return nil
+	}
+}
+
+// WithKeys signs with a rotated KeySet. It is validated, and its key
+// schedules computed, by New.
+func WithKeys(keys *KeySet) Option {
+	return func(s *Signer) error {
+		s.base.Keys = keys
+		return nil
+	}
+}
+
+// WithClock replaces time.Now for requests without a timestamp.
+func WithClock(now func() time.Time) Option {
+	return func(s *Signer) error {
+		s.base.Clock = now
+		return nil
+	}
+}
+
+// WithSkew corrects the clock by the offset learned by t.
+func WithSkew(t *SkewTracker) Option {
+	return func(s *Signer) error {
+		s.base.Skew = t
+		return nil
+	}
+}
+
+// WithRand replaces crypto/rand as the source of the random bytes. Each
+// signature reads its 8 bytes in one go, so concurrent calls each get a
+// contiguous slice of r.
+func WithRand(r io.Reader) Option {
+	return func(s *Signer) error {
+		s.rand = r
+		return nil
+	}
+}
+
+// WithObserver reports stage timings of every signature to o.
+func WithObserver(o Observer) Option {
+	return func(s *Signer) error {
+		s.base.Observer = o
+		return nil
+	}
+}
+
+// WithStrict makes the Argus encoder reject values it cannot represent.
+func WithStrict() Option {
+	return func(s *Signer) error {
+		s.base.Strict = true
+		return nil
+	}
+}
+
+// New returns a Signer configured by opts. Without options it signs like
+// SignRequest, with the platform taken from each request's query.
+func New(opts ...Option) (*Signer, error) {
+	s := &Signer{schedules: &scheduleCache{}}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.base.Platform != PlatformUnset {
+		// aid and version_name are left to each request, whose query may
+		// carry them.
+		prof, _ := s.base.Platform.Profile()
+		if s.base.LicenseID == 0 {
+			s.base.LicenseID = prof.LicenseID
+		}
+		if s.base.SdkVersionString == "" {
+			s.base.SdkVersionString = prof.SdkVersionString
+		}
+		if s.base.SdkVersionInt == 0 {
+			s.base.SdkVersionInt = prof.SdkVersionInt
+		}
+	}
+
+	if s.base.Keys != nil {
+		if err := s.base.Keys.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.schedules.get(s.base.Keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sign signs a request. cfg supplies the request (query, body, cookie and,
+// optionally, timestamp and device_id); its app constants and hooks, when
+// set, override the Signer's for this call. A Platform in cfg must match
+// the Signer's. ctx is checked before signing, which does not block.
+func (s *Signer) Sign(ctx context.Context, cfg SignConfig) (SignedHeaders, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cfg, err := s.apply(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return signRequest(cfg, s.schedules, nil)
+}
+
+// apply fills the zero fields of cfg from the Signer.
+func (s *Signer) apply(cfg SignConfig) (SignConfig, error) {
+	b := &s.base
+	switch {
+	case b.Platform == PlatformUnset:
+	case cfg.Platform == PlatformUnset:
+		cfg.Platform = b.Platform
+	case cfg.Platform != b.Platform:
+		return cfg, fmt.Errorf("ttsig: request platform %v does not match signer platform %v", cfg.Platform, b.Platform)
+	}
+
+	if cfg.SecDeviceID == "" {
+		cfg.SecDeviceID = b.SecDeviceID
+	}
+	if cfg.AppID == 0 {
+		cfg.AppID = b.AppID
+	}
+	if cfg.LicenseID == 0 {
+		cfg.LicenseID = b.LicenseID
+	}
+	if cfg.SdkVersionString == "" {
+		cfg.SdkVersionString = b.SdkVersionString
+	}
+	if cfg.SdkVersionInt == 0 {
+		cfg.SdkVersionInt = b.SdkVersionInt
+	}
+	if cfg.VersionName == "" && b.VersionName != "" && !cfg.queryHas("version_name") {
+		cfg.VersionName = b.VersionName
+	}
+	if cfg.Skew == nil {
+		cfg.Skew = b.Skew
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = b.Clock
+	}
+	if cfg.Keys == nil {
+		cfg.Keys = b.Keys
+	}
+	if cfg.Observer == nil {
+		cfg.Observer = b.Observer
+	}
+	cfg.Strict = cfg.Strict || b.Strict
+
+	if cfg.Rand == nil && s.rand != nil {
+		cfg.Rand = &signerRand{s: s}
+	}
+	return cfg, nil
+}
+
+// signerRand hands one signature its 8 bytes of the Signer's Rand. They
+// are drawn on the first read, so requests that fail before signing do
+// not consume any.
+type signerRand struct {
+	s   *Signer
+	buf *bytes.Reader
+	err error
+}
+
+func (r *signerRand) Read(p []byte) (int, error) {
+	if r.buf == nil && r.err == nil {
+		var b [8]byte
+		r.s.randMu.Lock()
+		_, r.err = io.ReadFull(r.s.rand, b[:])
+		r.s.randMu.Unlock()
+		r.buf = bytes.NewReader(b[:])
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.buf.Read(p)
+}
+
+// queryHas reports whether the request's query sets param. An unparsable
+// query reports false and fails later in Resolve.
+func (c *SignConfig) queryHas(param string) bool {
+	q := c.Query
+	if q == nil {
+		var err error
+		if q, err = ParseQuery(c.RawRequestParameters); err != nil {
+			return false
+		}
+	}
+	return q.Get(param) != ""
+}
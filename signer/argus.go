@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/md5"
-	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"math/rand"
-	"net/url"
 	"strconv"
 
 	"github.com/Skill/ttsig/crypto"
@@ -23,6 +23,23 @@ func pkcs7Pad(data []byte, blockSize int) []byte {
 	return append(data, bytes.Repeat([]byte{byte(pad)}, pad)...)
 }
 
+// pkcs7Unpad strips and checks PKCS7 padding.
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("pkcs7: length %d is not a multiple of %d", len(data), blockSize)
+	}
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > blockSize {
+		return nil, fmt.Errorf("pkcs7: invalid padding")
+	}
+	for _, b := range data[len(data)-pad:] {
+		if int(b) != pad {
+			return nil, fmt.Errorf("pkcs7: invalid padding")
+		}
+	}
+	return data[:len(data)-pad], nil
+}
+
 // ------------------------------------------------------------
 // encrypt_enc_pb (matches Python exactly)
 // ------------------------------------------------------------
@@ -81,155 +98,213 @@ func GetQueryHash(query string) []byte {
 	return out[:6]
 }
 
+// decryptEncPB reverses encryptEncPB. The first 8 bytes are left untouched by
+// the XOR, so they can be read back as the key once the buffer is reversed.
+func decryptEncPB(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := range data {
+		out[i] = data[len(data)-1-i]
+	}
+
+	if len(out) < 8 {
+		return out
+	}
+	x := out[:8]
+	for i := 8; i < len(out); i++ {
+		out[i] ^= x[i%8]
+	}
+
+	return out
+}
+
 // ------------------------------------------------------------
 // AES-CBC with MD5(key), MD5(iv)
 // ------------------------------------------------------------
-func aesCBCEncrypt(key, iv, plaintext []byte) ([]byte, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
+func aesCBCEncrypt(block cipher.Block, iv, plaintext []byte) []byte {
 	plaintext = pkcs7Pad(plaintext, aes.BlockSize)
 
 	out := make([]byte, len(plaintext))
 	mode := cipher.NewCBCEncrypter(block, iv)
 	mode.CryptBlocks(out, plaintext)
-	// fmt.Println("[aesCBCEncrypt] Return Value:")
-	// fmt.Println("out")
-	return out, nil
+	return out
+}
+
+func aesCBCDecrypt(block cipher.Block, iv, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("aes: ciphertext length %d is not a multiple of the block size", len(ciphertext))
+	}
+
+	out := make([]byte, len(ciphertext))
+	mode := cipher.NewCBCDecrypter(block, iv)
+	mode.CryptBlocks(out, ciphertext)
+	return pkcs7Unpad(out, aes.BlockSize)
 }
 
 // ------------------------------------------------------------
 // Encrypt() — main Argus encoder
 // ------------------------------------------------------------
 func Encrypt(x map[int]any) (string, error) {
-	// fmt.Println("ARGUS BEAN:")
-	// fmt.Println(x)
-	// Build ProtoBuf (your implementation has no constructor!)
-	pb := &ProtoBuf{Fields: []*ProtoField{}}
-
-	keys := []int{
-		1, 2, 3, 4, 5, 6, 7, 8, 9,
-		10, 12, 13, 14, 16, 20, 21, 25,
-	}
+	return EncryptWithKeys(nil, x)
+}
 
-	for _, k := range keys {
-		v := x[k]
-
-		switch t := v.(type) {
-		case int:
-			pb.PutVarint(k, uint64(t))
-		case int64:
-			pb.PutVarint(k, uint64(t))
-		case uint64:
-			pb.PutVarint(k, t)
-		case string:
-			pb.PutUtf8(k, t)
-		case []byte:
-			pb.PutBytes(k, t)
-		case map[int]any:
-			sub := &ProtoBuf{Fields: []*ProtoField{}}
-
-			// ⚠️ nested maps ALSO need ordered keys if used
-			subKeys := []int{ /* explicit order if present */ }
-			for _, kk := range subKeys {
-				vv := t[kk]
-				switch tv := vv.(type) {
-				case int:
-					sub.PutVarint(kk, uint64(tv))
-				case int64:
-					sub.PutVarint(kk, uint64(tv))
-				case uint64:
-					sub.PutVarint(kk, tv)
-				case string:
-					sub.PutUtf8(kk, tv)
-				case []byte:
-					sub.PutBytes(kk, tv)
-				}
-			}
-
-			subBytes, _ := sub.ToBytes()
-			pb.PutBytes(k, subBytes)
-		}
+// EncryptWithKeys is Encrypt using the Argus constants of keys.
+func EncryptWithKeys(keys *KeySet, x map[int]any) (string, error) {
+	return EncryptWithOptions(x, EncodeOptions{Keys: keys})
+}
+
+// EncryptWithOptions is Encrypt with explicit keys and strictness.
+func EncryptWithOptions(x map[int]any, opts EncodeOptions) (string, error) {
+	keys, err := opts.Keys.checked()
+	if err != nil {
+		return "", err
 	}
 
 	// The unpadded protobuf string
-	raw, _ := pb.ToBytes()
+	raw, err := EncodeBean(x, opts.Strict)
+	if err != nil {
+		return "", err
+	}
+
+	s, err := newSchedule(keys)
+	if err != nil {
+		return "", err
+	}
+	return s.encryptArgus(raw), nil
+}
 
-	//Padded protobuf string
-	protobuf := pkcs7Pad(raw, aes.BlockSize)
-	n := len(protobuf)
+// ------------------------------------------------------------
+// Decrypt() — reverses Encrypt()
+// ------------------------------------------------------------
 
-	// fmt.Printf("Protobuf: %x\n", protobuf)
+// DecryptRaw undoes every layer of an x-argus header and returns the
+// serialized protobuf bean.
+func DecryptRaw(xArgus string) ([]byte, error) {
+	return DecryptRawWithKeys(nil, xArgus)
+}
 
-	// Fixed sign key
-	signKey := []byte{
-		0xac, 0x1a, 0xda, 0xae, 0x95, 0xa7, 0xaf, 0x94,
-		0xa5, 0x11, 0x4a, 0xb3, 0xb3, 0xa9, 0x7d, 0xd8,
-		0x00, 0x50, 0xaa, 0x0a, 0x39, 0x31, 0x4c, 0x40,
-		0x52, 0x8c, 0xae, 0xc9, 0x52, 0x56, 0xc2, 0x8c,
+// DecryptRawWithKeys is DecryptRaw using the Argus constants of keys.
+func DecryptRawWithKeys(keys *KeySet, xArgus string) ([]byte, error) {
+	keys, err := keys.checked()
+	if err != nil {
+		return nil, err
 	}
 
-	sm3Output := []byte{
-		0xfc, 0x78, 0xe0, 0xa9, 0x65, 0x7a, 0x0c, 0x74,
-		0x8c, 0xe5, 0x15, 0x59, 0x90, 0x3c, 0xcf, 0x03,
-		0x51, 0x0e, 0x51, 0xd3, 0xcf, 0xf2, 0x32, 0xd7,
-		0x13, 0x43, 0xe8, 0x8a, 0x32, 0x1c, 0x53, 0x04,
+	s, err := newSchedule(keys)
+	if err != nil {
+		return nil, err
 	}
+	return s.decryptArgus(xArgus)
+}
 
-	// Build SIMON key list (<QQ>, <QQ>)
-	keyList := [4]uint64{
-		binary.LittleEndian.Uint64(sm3Output[0:8]),
-		binary.LittleEndian.Uint64(sm3Output[8:16]),
-		binary.LittleEndian.Uint64(sm3Output[16:24]),
-		binary.LittleEndian.Uint64(sm3Output[24:32]),
-	}
-	// fmt.Println("[GO][ARGUS] SIMON KEY LIST")
-	// fmt.Println(keyList)
+// Decrypt reverses Encrypt and parses the bean into a ProtoBuf.
+func Decrypt(xArgus string) (*ProtoBuf, error) {
+	return DecryptWithKeys(nil, xArgus)
+}
 
-	encPB := make([]byte, n)
+// DecryptWithKeys is Decrypt using the Argus constants of keys.
+func DecryptWithKeys(keys *KeySet, xArgus string) (*ProtoBuf, error) {
+	raw, err := DecryptRawWithKeys(keys, xArgus)
+	if err != nil {
+		return nil, err
+	}
+	return NewProtoBufFromBytes(raw)
+}
 
-	for i := 0; i < n/16; i++ {
-		pt0 := binary.LittleEndian.Uint64(protobuf[i*16 : i*16+8])
-		pt1 := binary.LittleEndian.Uint64(protobuf[i*16+8 : i*16+16])
+// ------------------------------------------------------------
+// ArgusParams — every input of the Argus bean
+// ------------------------------------------------------------
 
-		ct := crypto.SimonEnc([2]uint64{pt0, pt1}, keyList, 0)
+// DefaultVersionName is used for field 7 when neither the query nor the
+// caller supplies a version_name.
+const DefaultVersionName = "39.6.3"
+
+// ErrNoDeviceID is returned when a bean is built without a device_id.
+var ErrNoDeviceID = errors.New("argus: device_id is required")
+
+// ArgusParams holds the inputs of the Argus bean. Query is the raw query
+// string exactly as sent; BodyStub is the x-ss-stub value ("" for no body).
+type ArgusParams struct {
+	Query         string
+	BodyStub      string
+	Timestamp     int64
+	AID           int
+	LicenseID     int
+	Platform      Platform
+	DeviceID      string
+	VersionName   string
+	SecDeviceID   string
+	SdkVersion    string
+	SdkVersionInt int
+
+	// Keys overrides the built-in constants when set.
+	Keys *KeySet
+	// Strict rejects bean values the encoder cannot represent.
+	Strict bool
+	// Rand supplies the 4 bytes behind field 3. When nil, math/rand is used.
+	Rand io.Reader
+}
 
-		binary.LittleEndian.PutUint64(encPB[i*16:], ct[0])
-		binary.LittleEndian.PutUint64(encPB[i*16+8:], ct[1])
+// Bean builds the field map that Encrypt serializes.
+func (p ArgusParams) Bean() (map[int]any, error) {
+	if p.DeviceID == "" {
+		return nil, ErrNoDeviceID
 	}
 
-	// fmt.Printf("ENC_PB Hex: %x\n", encPB)
-
-	// prefix + encrypt_enc_pb + wrap with header/footer
-	buf := append([]byte{0xf2, 0xf7, 0xfc, 0xff, 0xf2, 0xf7, 0xfc, 0xff}, encPB...)
-	buf = encryptEncPB(buf, n+8)
-
-	buf = append([]byte{0xa6, 0x6e, 0xad, 0x9f, 0x77, 0x01, 0xd0, 0x0c, 0x18}, buf...)
-	buf = append(buf, []byte("ao")...)
+	random := rand.Int31()
+	if p.Rand != nil {
+		var b [4]byte
+		if _, err := io.ReadFull(p.Rand, b[:]); err != nil {
+			return nil, fmt.Errorf("argus: reading random field: %w", err)
+		}
+		random = int32(binary.LittleEndian.Uint32(b[:]) & 0x7FFFFFFF)
+	}
+	prof, err := p.Platform.Profile()
+	if err != nil {
+		return nil, err
+	}
 
-	// fmt.Printf("Wrapped ENC_PB Buffer: %x\n", buf)
+	versionName := p.VersionName
+	if versionName == "" {
+		versionName = prof.VersionName
+	}
 
-	// AES-CBC
-	key := md5.Sum(signKey[:16])
-	iv := md5.Sum(signKey[16:])
-	// fmt.Printf("[GO] AES Sign Key (Hex): len=%d, hex=%x\n", len(key), key)
-	// fmt.Printf("[GO] AES IV (Hex): len=%d, hex=%x\n", len(iv), iv)
+	bean := map[int]any{
+		1:  uint64(0x20200929) << 1,
+		2:  2,
+		3:  random,
+		4:  strconv.Itoa(p.AID),
+		5:  p.DeviceID,
+		6:  strconv.Itoa(p.LicenseID),
+		7:  versionName,
+		8:  p.SdkVersion,
+		9:  p.SdkVersionInt,
+		10: append([]byte(nil), prof.EnvCode...),
+		12: uint64(p.Timestamp) << 1,
+		13: GetBodyHash(p.BodyStub),
+		14: GetQueryHash(p.Query),
+		16: p.SecDeviceID,
+		20: prof.PSKVersion,
+		21: prof.CallType,
+		25: 2,
+	}
+	return bean, nil
+}
 
-	ciphertext, err := aesCBCEncrypt(key[:], iv[:], buf)
+// Sign builds the bean from p and encrypts it into an x-argus value.
+func Sign(p ArgusParams) (string, error) {
+	bean, err := p.Bean()
 	if err != nil {
 		return "", err
 	}
-	// fmt.Printf("[GO] AES Ciphertext: %x\n", ciphertext)
-
-	final := append([]byte{0xf2, 0x81}, ciphertext...)
-
-	return base64.StdEncoding.EncodeToString(final), nil
+	return EncryptWithOptions(bean, EncodeOptions{Keys: p.Keys, Strict: p.Strict})
 }
 
 // ------------------------------------------------------------
 // GetSign — identical to Python Argus.get_sign()
 // ------------------------------------------------------------
+
+// GetSign takes device_id and version_name from the query. New code should
+// fill an ArgusParams and call Sign, which lets the caller supply them.
 func GetSign(
 	queryhash string,
 	data string,
@@ -242,30 +317,27 @@ func GetSign(
 	sdkVersionInt int,
 ) (string, error) {
 
-	params, _ := url.ParseQuery(queryhash)
-
-	deviceID := params["device_id"][0]
-	versionName := "39.6.3"
+	params, err := ParseQuery(queryhash)
+	if err != nil {
+		return "", err
+	}
 
-	x := map[int]any{
-		1:  uint64(0x20200929) << 1,
-		2:  2,
-		3:  rand.Int31(),
-		4:  strconv.Itoa(aid),
-		5:  deviceID,
-		6:  strconv.Itoa(licenseID),
-		7:  versionName,
-		8:  sdkVersion,
-		9:  sdkVersionInt,
-		10: make([]byte, 8),
-		12: uint64(timestamp) << 1,
-		13: GetBodyHash(data),
-		14: GetQueryHash(queryhash),
-		16: secDeviceID,
-		20: "none",
-		21: 738,
-		25: 2,
+	deviceID := params.DeviceID()
+	if deviceID == "" {
+		return "", fmt.Errorf("argus: query has no device_id")
 	}
 
-	return Encrypt(x)
+	return Sign(ArgusParams{
+		Query:         queryhash,
+		BodyStub:      data,
+		Timestamp:     timestamp,
+		AID:           aid,
+		LicenseID:     licenseID,
+		Platform:      Platform(platform),
+		DeviceID:      deviceID,
+		VersionName:   params.VersionName(),
+		SecDeviceID:   secDeviceID,
+		SdkVersion:    sdkVersion,
+		SdkVersionInt: sdkVersionInt,
+	})
 }
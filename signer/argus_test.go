@@ -0,0 +1,36 @@
+package signer
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzArgusRoundTrip encrypts a bean with a varint, an int32 and two
+// length-delimited fields and checks every value survives decryption.
+func FuzzArgusRoundTrip(f *testing.F) {
+	f.Fuzz(func(t *testing.T, n uint64, random int32, deviceID string, data []byte) {
+		bean := map[int]any{1: n, 3: random, 5: deviceID, 10: data}
+		header, err := EncryptWithOptions(bean, EncodeOptions{Strict: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pb, err := Decrypt(header)
+		if err != nil {
+			t.Fatalf("%s: %v", header, err)
+		}
+
+		if got, _ := pb.GetInt(1); got != n {
+			t.Errorf("field 1: %d, want %d", got, n)
+		}
+		// int32 is sign-extended like in protobuf.
+		if got, _ := pb.GetInt(3); int64(got) != int64(random) {
+			t.Errorf("field 3: %d, want %d", int64(got), random)
+		}
+		if got, _ := pb.GetBytes(5); string(got) != deviceID {
+			t.Errorf("field 5: %q, want %q", got, deviceID)
+		}
+		if got, _ := pb.GetBytes(10); !bytes.Equal(got, data) {
+			t.Errorf("field 10: %x, want %x", got, data)
+		}
+	})
+}
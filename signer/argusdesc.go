@@ -0,0 +1,28 @@
+package signer
+
+import (
+	_ "embed"
+	"sync"
+)
+
+//go:embed argus.proto
+var argusProto string
+
+var argusDesc = sync.OnceValues(func() (*FileDescriptor, error) {
+	return ParseProto(argusProto)
+})
+
+// ArgusProto returns the embedded argus.proto source.
+func ArgusProto() string {
+	return argusProto
+}
+
+// ArgusDescriptor returns the descriptor of the Argus bean, parsed from the
+// embedded argus.proto.
+func ArgusDescriptor() *MessageDescriptor {
+	fd, err := argusDesc()
+	if err != nil {
+		panic("signer: embedded argus.proto: " + err.Error())
+	}
+	return fd.Message("Argus")
+}
@@ -0,0 +1,159 @@
+package signer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ------------------------------------------------------------
+// Argus bean encoding
+// ------------------------------------------------------------
+
+// Fixed32 and Fixed64 request fixed-width wire types for a bean value
+// instead of a varint.
+type (
+	Fixed32 uint32
+	Fixed64 uint64
+)
+
+// beanOrder is the Argus schema: the fields the bean may carry, in the
+// order they are written.
+var beanOrder = []int{
+	1, 2, 3, 4, 5, 6, 7, 8, 9,
+	10, 11, 12, 13, 14, 15, 16, 20, 21, 23, 25,
+}
+
+// BeanError reports a bean value the encoder cannot represent, or a field
+// outside the schema.
+type BeanError struct {
+	Field int
+	// Parents holds the enclosing field numbers when Field is inside a
+	// nested message, outermost first.
+	Parents []int
+	Value   any
+	Reason  string
+}
+
+func (e *BeanError) Error() string {
+	path := ""
+	for _, p := range e.Parents {
+		path += strconv.Itoa(p) + "."
+	}
+	return fmt.Sprintf("argus: field %s%d (%T): %s", path, e.Field, e.Value, e.Reason)
+}
+
+// EncodeOptions controls how a bean is turned into protobuf.
+type EncodeOptions struct {
+	// Keys overrides the built-in constants when set.
+	Keys *KeySet
+	// Strict makes unsupported values and fields outside the schema an
+	// error instead of silently leaving them out.
+	Strict bool
+}
+
+// EncodeBean serializes a bean to protobuf in schema order. Nested
+// map[int]any values become embedded messages with ascending field numbers.
+func EncodeBean(x map[int]any, strict bool) ([]byte, error) {
+	pb, err := beanToProto(x, beanOrder, strict)
+	if err != nil {
+		return nil, err
+	}
+	return pb.ToBytes()
+}
+
+func beanToProto(x map[int]any, order []int, strict bool) (*ProtoBuf, error) {
+	if strict {
+		known := make(map[int]bool, len(order))
+		for _, k := range order {
+			known[k] = true
+		}
+		unknown := make([]int, 0)
+		for k := range x {
+			if !known[k] {
+				unknown = append(unknown, k)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Ints(unknown)
+			return nil, &BeanError{Field: unknown[0], Value: x[unknown[0]], Reason: "field is not in the Argus schema"}
+		}
+	}
+
+	pb := &ProtoBuf{Fields: []*ProtoField{}}
+	for _, k := range order {
+		v, ok := x[k]
+		if !ok {
+			continue
+		}
+		if err := putBeanValue(pb, k, v, strict); err != nil {
+			return nil, err
+		}
+	}
+	return pb, nil
+}
+
+// putBeanValue appends v as field idx. Values it cannot encode are skipped
+// unless strict is set.
+func putBeanValue(pb *ProtoBuf, idx int, v any, strict bool) error {
+	switch t := v.(type) {
+	case int:
+		pb.PutVarint(idx, uint64(t))
+	case int8:
+		pb.PutVarint(idx, uint64(t))
+	case int16:
+		pb.PutVarint(idx, uint64(t))
+	case int32:
+		pb.PutVarint(idx, uint64(t))
+	case int64:
+		pb.PutVarint(idx, uint64(t))
+	case uint:
+		pb.PutVarint(idx, uint64(t))
+	case uint8:
+		pb.PutVarint(idx, uint64(t))
+	case uint16:
+		pb.PutVarint(idx, uint64(t))
+	case uint32:
+		pb.PutVarint(idx, uint64(t))
+	case uint64:
+		pb.PutVarint(idx, t)
+	case bool:
+		var b uint64
+		if t {
+			b = 1
+		}
+		pb.PutVarint(idx, b)
+	case Fixed32:
+		pb.PutInt32(idx, uint32(t))
+	case Fixed64:
+		pb.PutInt64(idx, uint64(t))
+	case string:
+		pb.PutUtf8(idx, t)
+	case []byte:
+		pb.PutBytes(idx, t)
+	case map[int]any:
+		keys := make([]int, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Ints(keys)
+
+		sub, err := beanToProto(t, keys, strict)
+		if err != nil {
+			if be, ok := err.(*BeanError); ok {
+				be.Parents = append([]int{idx}, be.Parents...)
+			}
+			return err
+		}
+		subBytes, err := sub.ToBytes()
+		if err != nil {
+			return err
+		}
+		pb.PutBytes(idx, subBytes)
+	default:
+		if strict {
+			return &BeanError{Field: idx, Value: v, Reason: "unsupported value type"}
+		}
+	}
+	return nil
+}
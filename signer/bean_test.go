@@ -0,0 +1,152 @@
+package signer
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// beanVectors pin the protobuf bytes of EncodeBean. before holds what the
+// encoder produced until it handled every integer kind and stopped masking
+// varints to 32 bits; the cases where it differs are the fixes.
+var beanVectors = []struct {
+	name   string
+	bean   map[int]any
+	before string
+	after  string
+}{
+	{
+		name: "android bean without field 3",
+		bean: map[int]any{
+			1: uint64(0x20200929) << 1, 2: 2, 4: "1233", 5: "7300000000000000001",
+			6: "1611921764", 7: "39.6.3", 8: "v05.00.06-ov-android", 9: 167775296,
+			10: make([]byte, 8), 12: uint64(1700000000) << 1,
+			13: []byte{1, 2, 3, 4, 5, 6}, 14: []byte{7, 8, 9, 10, 11, 12},
+			16: "", 20: "none", 21: 738, 25: 2,
+		},
+		before: "08d2a480820410022204313233332a1337333030303030303030303030303030303031320a313631313932313736343a0633392e362e3342147630352e30302e30362d6f762d616e64726f696448c0988050520800000000000000006080c49fd50c6a0601020304050672060708090a0b0c820100a201046e6f6e65a801e205c80102",
+		after:  "08d2a480820410022204313233332a1337333030303030303030303030303030303031320a313631313932313736343a0633392e362e3342147630352e30302e30362d6f762d616e64726f696448c0988050520800000000000000006080c49fd50c6a0601020304050672060708090a0b0c820100a201046e6f6e65a801e205c80102",
+	},
+	{
+		name:   "varints below 2^14",
+		bean:   map[int]any{1: 1, 2: 127, 4: 300, 5: 16383},
+		before: "0801107f20ac0228ff7f",
+		after:  "0801107f20ac0228ff7f",
+	},
+	{
+		// A final base-128 group of exactly 0x80 used to be written as 0x00.
+		name:   "varint 128",
+		bean:   map[int]any{1: 128},
+		before: "0800",
+		after:  "088001",
+	},
+	{
+		name:   "varint 2^14",
+		bean:   map[int]any{6: int64(16384)},
+		before: "308000",
+		after:  "30808001",
+	},
+	{
+		// Values were masked to 32 bits.
+		name:   "varint above 32 bits",
+		bean:   map[int]any{1: uint64(1) << 35},
+		before: "0800",
+		after:  "08808080808001",
+	},
+	{
+		name:   "negative int",
+		bean:   map[int]any{1: -1},
+		before: "08ffffffff0f",
+		after:  "08ffffffffffffffffff01",
+	},
+	{
+		// The random field is an int32 and used to be dropped.
+		name:   "int32 field 3",
+		bean:   map[int]any{3: int32(0x12345678)},
+		before: "",
+		after:  "18f8acd19101",
+	},
+	{
+		name:  "other integer kinds, bool and fixed width",
+		bean:  map[int]any{1: int8(-1), 2: uint16(300), 4: true, 5: Fixed32(0x01020304), 6: Fixed64(0x0102030405060708), 7: uint8(7)},
+		after: "08ffffffffffffffffff0110ac0220012d040302013108070605040302013807",
+	},
+}
+
+func TestEncodeBeanVectors(t *testing.T) {
+	for _, tt := range beanVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, strict := range []bool{false, true} {
+				got, err := EncodeBean(tt.bean, strict)
+				if err != nil {
+					t.Fatalf("strict=%v: %v", strict, err)
+				}
+				if hex.EncodeToString(got) != tt.after {
+					t.Errorf("strict=%v:\ngot  %x\nwant %s", strict, got, tt.after)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeBeanStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		bean    map[int]any
+		field   int
+		parents []int
+		msg     string
+	}{
+		{
+			name:  "field outside the schema",
+			bean:  map[int]any{1: 1, 17: "x", 30: 2},
+			field: 17,
+			msg:   "argus: field 17 (string): field is not in the Argus schema",
+		},
+		{
+			name:  "unsupported type",
+			bean:  map[int]any{5: 1.5},
+			field: 5,
+			msg:   "argus: field 5 (float64): unsupported value type",
+		},
+		{
+			name:    "unsupported type in a nested message",
+			bean:    map[int]any{15: map[int]any{1: 1, 2: []int{1}}},
+			field:   2,
+			parents: []int{15},
+			msg:     "argus: field 15.2 ([]int): unsupported value type",
+		},
+		{
+			name:  "nil value",
+			bean:  map[int]any{7: nil},
+			field: 7,
+			msg:   "argus: field 7 (<nil>): unsupported value type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := EncodeBean(tt.bean, true)
+			var be *BeanError
+			if !errors.As(err, &be) {
+				t.Fatalf("error %v, want a BeanError", err)
+			}
+			if be.Field != tt.field || len(be.Parents) != len(tt.parents) {
+				t.Errorf("field %v.%d, want %v.%d", be.Parents, be.Field, tt.parents, tt.field)
+			}
+			if err.Error() != tt.msg {
+				t.Errorf("got  %s\nwant %s", err, tt.msg)
+			}
+
+			// Without strict the value is left out.
+			if _, err := EncodeBean(tt.bean, false); err != nil {
+				t.Errorf("lenient: %v", err)
+			}
+		})
+	}
+
+	_, err := EncryptWithOptions(map[int]any{5: 1.5}, EncodeOptions{Strict: true})
+	if err == nil {
+		t.Error("EncryptWithOptions ignored Strict")
+	}
+}
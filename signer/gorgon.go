@@ -13,6 +13,13 @@ type Gorgon struct {
 	Params  string
 	Data    string
 	Cookies string
+
+	// DataMD5 is the hex MD5 of the body. When set it is used instead of
+	// hashing Data, so callers that already digested the body skip a pass.
+	DataMD5 string
+
+	// Keys overrides the built-in constants when set.
+	Keys *KeySet
 }
 
 // -----------------------------
@@ -29,7 +36,9 @@ func md5Hex(s string) string {
 func (g *Gorgon) getBaseString() string {
 	base := md5Hex(g.Params)
 
-	if g.Data != "" {
+	if g.DataMD5 != "" {
+		base += strings.ToLower(g.DataMD5)
+	} else if g.Data != "" {
 		base += md5Hex(g.Data)
 	} else {
 		base += strings.Repeat("0", 32)
@@ -44,6 +53,12 @@ func (g *Gorgon) getBaseString() string {
 	return base
 }
 
+// BaseString returns the string the Gorgon input is built from: the hex
+// MD5 of the query, body and cookies, with zeros for absent ones.
+func (g *Gorgon) BaseString() string {
+	return g.getBaseString()
+}
+
 // -----------------------------
 // Python: reverse()
 // -----------------------------
@@ -68,15 +83,12 @@ func rbit(num byte) byte {
 // -----------------------------
 // Python: encrypt()
 // -----------------------------
-func (g *Gorgon) encrypt(base string) map[string]string {
-	length := 0x14
-
-	key := []byte{
-		0xDF, 0x77, 0xB9, 0x40, 0xB9,
-		0x9B, 0x84, 0x83, 0xD1, 0xB9,
-		0xCB, 0xD1, 0xF7, 0xC2, 0xB9,
-		0x85, 0xC3, 0xD0, 0xFB, 0xC3,
+func (g *Gorgon) encrypt(base string) (map[string]string, error) {
+	keys, err := g.Keys.checked()
+	if err != nil {
+		return nil, err
 	}
+	length := 0x14
 
 	paramList := make([]byte, 0, length)
 
@@ -107,7 +119,7 @@ func (g *Gorgon) encrypt(base string) map[string]string {
 	// eor_result_list = [A ^ B]
 	eor := make([]byte, length)
 	for i := 0; i < length; i++ {
-		eor[i] = paramList[i] ^ key[i]
+		eor[i] = paramList[i] ^ keys.GorgonKey[i]
 	}
 
 	// main transform loop
@@ -125,17 +137,87 @@ func (g *Gorgon) encrypt(base string) map[string]string {
 		result.WriteString(fmt.Sprintf("%02x", b))
 	}
 
+	// x-khronos and x-ss-req-ticket are left to the caller, which owns the
+	// millisecond timestamp.
 	return map[string]string{
-		"x-ss-req-ticket": fmt.Sprintf("%d", g.Unix*1000),
-		"x-khronos":       fmt.Sprintf("%d", g.Unix),
-		"x-gorgon":        "0404b0d30000" + result.String(),
-	}
+		"x-gorgon": keys.GorgonVersion + result.String(),
+	}, nil
 }
 
 // -----------------------------
 // Python: get_value()
 // -----------------------------
-func (g *Gorgon) GetValue() map[string]string {
+
+// GetValue returns the x-gorgon header. It fails only when Keys is set
+// and invalid.
+func (g *Gorgon) GetValue() (map[string]string, error) {
 	base := g.getBaseString()
 	return g.encrypt(base)
 }
+
+// -----------------------------
+// Decoding
+// -----------------------------
+
+// GorgonFields holds the plaintext recovered from an x-gorgon header.
+// Each hash is the first four bytes of the MD5 of the corresponding input,
+// or all zero when that input was empty.
+type GorgonFields struct {
+	Version    string
+	QueryHash  [4]byte
+	BodyHash   [4]byte
+	CookieHash [4]byte
+	Constant   [4]byte
+	Unix       int64
+}
+
+// DecodeGorgon reverses encrypt() and returns the fields of an x-gorgon header.
+func DecodeGorgon(header string) (*GorgonFields, error) {
+	return DecodeGorgonWithKeys(nil, header)
+}
+
+// DecodeGorgonWithKeys is DecodeGorgon using the Gorgon constants of keys.
+func DecodeGorgonWithKeys(keys *KeySet, header string) (*GorgonFields, error) {
+	keys, err := keys.checked()
+	if err != nil {
+		return nil, err
+	}
+
+	gorgonVersion := keys.GorgonVersion
+
+	if len(header) != len(gorgonVersion)+2*0x14 {
+		return nil, fmt.Errorf("gorgon: invalid header length %d", len(header))
+	}
+	if !strings.HasPrefix(header, gorgonVersion) {
+		return nil, fmt.Errorf("gorgon: unknown version prefix %q", header[:len(gorgonVersion)])
+	}
+
+	eor, err := hex.DecodeString(header[len(gorgonVersion):])
+	if err != nil {
+		return nil, fmt.Errorf("gorgon: %w", err)
+	}
+
+	// Undo the transform loop back to front: byte i was derived from the
+	// original byte i+1, which is already restored by the time we reach i,
+	// except for the last byte which used the already transformed byte 0.
+	length := len(eor)
+	for i := length - 1; i >= 0; i-- {
+		F := byte((^uint32(eor[i]) ^ uint32(length)) & 0xFF)
+		E := rbit(F)
+		D := eor[(i+1)%length]
+		eor[i] = reverseByte(E ^ D)
+	}
+
+	for i := range eor {
+		eor[i] ^= keys.GorgonKey[i]
+	}
+
+	f := &GorgonFields{Version: gorgonVersion}
+	copy(f.QueryHash[:], eor[0:4])
+	copy(f.BodyHash[:], eor[4:8])
+	copy(f.CookieHash[:], eor[8:12])
+	copy(f.Constant[:], eor[12:16])
+	f.Unix = int64(uint32(eor[16])<<24 | uint32(eor[17])<<16 | uint32(eor[18])<<8 | uint32(eor[19]))
+
+	return f, nil
+}
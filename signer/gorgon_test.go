@@ -0,0 +1,36 @@
+package signer
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// FuzzGorgonParse decodes arbitrary headers. Whatever decodes must encode
+// back to the same header, since every layer of x-gorgon is invertible.
+func FuzzGorgonParse(f *testing.F) {
+	f.Fuzz(func(t *testing.T, header string) {
+		fields, err := DecodeGorgon(header)
+		if err != nil {
+			return
+		}
+		// encrypt always writes the fixed constant.
+		if fields.Constant != [4]byte{0x00, 0x06, 0x0b, 0x1c} {
+			return
+		}
+
+		// encrypt reads the first four bytes of each 16-byte MD5.
+		pad := strings.Repeat("0", 24)
+		base := hex.EncodeToString(fields.QueryHash[:]) + pad +
+			hex.EncodeToString(fields.BodyHash[:]) + pad +
+			hex.EncodeToString(fields.CookieHash[:]) + pad
+		g := &Gorgon{Unix: fields.Unix}
+		out, err := g.encrypt(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.ToLower(header); out["x-gorgon"] != want {
+			t.Errorf("re-encoded\ngot  %s\nwant %s", out["x-gorgon"], want)
+		}
+	})
+}
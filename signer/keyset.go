@@ -0,0 +1,143 @@
+package signer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// KeySet — every constant the three algorithms depend on
+// ------------------------------------------------------------
+
+// KeySet groups the keys and framing bytes of Argus, Gorgon and Ladon, so
+// a rotated key is a data change rather than a code change. A nil *KeySet
+// means DefaultKeySet wherever one is accepted.
+type KeySet struct {
+	// ArgusSignKey is split in two halves whose MD5s are the AES key and IV.
+	ArgusSignKey []byte
+	// ArgusSimonKey is the precomputed SM3 digest used as the SIMON key.
+	ArgusSimonKey []byte
+	// ArgusXorPrefix is prepended to the SIMON output and XORed over it.
+	ArgusXorPrefix []byte
+	// ArgusHeader and ArgusFooter wrap the XORed buffer before AES.
+	ArgusHeader []byte
+	ArgusFooter []byte
+	// ArgusPrefix is prepended to the AES ciphertext before base64.
+	ArgusPrefix []byte
+
+	// GorgonKey is XORed with the parameter list before the byte transform.
+	GorgonKey []byte
+	// GorgonVersion is the hex prefix of every x-gorgon value.
+	GorgonVersion string
+
+	// LadonRounds is the number of rounds of the Ladon block function.
+	LadonRounds int
+}
+
+// ladonMaxRounds keeps the key schedule inside the 272+16 byte hash table.
+const ladonMaxRounds = (272+16)/8 - 2
+
+var defaultKeySet = &KeySet{
+	ArgusSignKey: []byte{
+		0xac, 0x1a, 0xda, 0xae, 0x95, 0xa7, 0xaf, 0x94,
+		0xa5, 0x11, 0x4a, 0xb3, 0xb3, 0xa9, 0x7d, 0xd8,
+		0x00, 0x50, 0xaa, 0x0a, 0x39, 0x31, 0x4c, 0x40,
+		0x52, 0x8c, 0xae, 0xc9, 0x52, 0x56, 0xc2, 0x8c,
+	},
+	ArgusSimonKey: []byte{
+		0xfc, 0x78, 0xe0, 0xa9, 0x65, 0x7a, 0x0c, 0x74,
+		0x8c, 0xe5, 0x15, 0x59, 0x90, 0x3c, 0xcf, 0x03,
+		0x51, 0x0e, 0x51, 0xd3, 0xcf, 0xf2, 0x32, 0xd7,
+		0x13, 0x43, 0xe8, 0x8a, 0x32, 0x1c, 0x53, 0x04,
+	},
+	ArgusXorPrefix: []byte{0xf2, 0xf7, 0xfc, 0xff, 0xf2, 0xf7, 0xfc, 0xff},
+	ArgusHeader:    []byte{0xa6, 0x6e, 0xad, 0x9f, 0x77, 0x01, 0xd0, 0x0c, 0x18},
+	ArgusFooter:    []byte("ao"),
+	ArgusPrefix:    []byte{0xf2, 0x81},
+
+	GorgonKey: []byte{
+		0xDF, 0x77, 0xB9, 0x40, 0xB9,
+		0x9B, 0x84, 0x83, 0xD1, 0xB9,
+		0xCB, 0xD1, 0xF7, 0xC2, 0xB9,
+		0x85, 0xC3, 0xD0, 0xFB, 0xC3,
+	},
+	GorgonVersion: "0404b0d30000",
+
+	LadonRounds: 0x22,
+}
+
+// DefaultKeySet returns a copy of the built-in keys.
+func DefaultKeySet() *KeySet {
+	return defaultKeySet.Clone()
+}
+
+// Clone returns a deep copy of k.
+func (k *KeySet) Clone() *KeySet {
+	c := *k
+	for _, b := range []*[]byte{
+		&c.ArgusSignKey, &c.ArgusSimonKey, &c.ArgusXorPrefix,
+		&c.ArgusHeader, &c.ArgusFooter, &c.ArgusPrefix, &c.GorgonKey,
+	} {
+		*b = append([]byte(nil), (*b)...)
+	}
+	return &c
+}
+
+// ErrInvalidKeySet is wrapped by every error Validate returns.
+var ErrInvalidKeySet = errors.New("keyset: invalid key set")
+
+// Validate checks every length the algorithms rely on.
+func (k *KeySet) Validate() error {
+	exact := []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"ArgusSignKey", len(k.ArgusSignKey), 32},
+		{"ArgusSimonKey", len(k.ArgusSimonKey), 32},
+		{"ArgusXorPrefix", len(k.ArgusXorPrefix), 8},
+		{"ArgusHeader", len(k.ArgusHeader), 9},
+		{"ArgusFooter", len(k.ArgusFooter), 2},
+		{"GorgonKey", len(k.GorgonKey), 0x14},
+	}
+	for _, e := range exact {
+		if e.got != e.want {
+			return fmt.Errorf("%w: %s must be %d bytes, got %d", ErrInvalidKeySet, e.name, e.want, e.got)
+		}
+	}
+
+	if len(k.ArgusPrefix) == 0 {
+		return fmt.Errorf("%w: ArgusPrefix must not be empty", ErrInvalidKeySet)
+	}
+	if _, err := hex.DecodeString(k.GorgonVersion); err != nil || k.GorgonVersion == "" {
+		return fmt.Errorf("%w: GorgonVersion must be non-empty hex, got %q", ErrInvalidKeySet, k.GorgonVersion)
+	}
+	if k.LadonRounds < 1 || k.LadonRounds > ladonMaxRounds {
+		return fmt.Errorf("%w: LadonRounds must be between 1 and %d, got %d", ErrInvalidKeySet, ladonMaxRounds, k.LadonRounds)
+	}
+	return nil
+}
+
+// checked lets every entry point accept a nil *KeySet and rejects an
+// invalid one.
+func (k *KeySet) checked() (*KeySet, error) {
+	if k == nil {
+		return defaultKeySet, nil
+	}
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// simonKey builds the SIMON key list (<QQ>, <QQ>) from ArgusSimonKey.
+func (k *KeySet) simonKey() [4]uint64 {
+	return [4]uint64{
+		binary.LittleEndian.Uint64(k.ArgusSimonKey[0:8]),
+		binary.LittleEndian.Uint64(k.ArgusSimonKey[8:16]),
+		binary.LittleEndian.Uint64(k.ArgusSimonKey[16:24]),
+		binary.LittleEndian.Uint64(k.ArgusSimonKey[24:32]),
+	}
+}
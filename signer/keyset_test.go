@@ -0,0 +1,156 @@
+package signer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// customKeySet returns a valid KeySet that differs from the default in
+// every field.
+func customKeySet() *KeySet {
+	k := DefaultKeySet()
+	for i := range k.ArgusSignKey {
+		k.ArgusSignKey[i] ^= 0x5a
+	}
+	for i := range k.ArgusSimonKey {
+		k.ArgusSimonKey[i] ^= 0x33
+	}
+	k.ArgusXorPrefix = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	k.ArgusHeader = []byte{0xa7, 0x6e, 0xad, 0x9f, 0x77, 0x01, 0xd0, 0x0c, 0x19}
+	k.ArgusFooter = []byte("ap")
+	k.ArgusPrefix = []byte{0xf3, 0x82, 0x01}
+	for i := range k.GorgonKey {
+		k.GorgonKey[i]++
+	}
+	k.GorgonVersion = "0405b0d30000"
+	k.LadonRounds = 0x10
+	return k
+}
+
+func TestKeySetValidate(t *testing.T) {
+	if err := DefaultKeySet().Validate(); err != nil {
+		t.Fatalf("default keys: %v", err)
+	}
+	if err := customKeySet().Validate(); err != nil {
+		t.Fatalf("custom keys: %v", err)
+	}
+
+	tests := []struct {
+		field  string
+		change func(*KeySet)
+	}{
+		{"ArgusSignKey", func(k *KeySet) { k.ArgusSignKey = k.ArgusSignKey[:31] }},
+		{"ArgusSimonKey", func(k *KeySet) { k.ArgusSimonKey = nil }},
+		{"ArgusXorPrefix", func(k *KeySet) { k.ArgusXorPrefix = append(k.ArgusXorPrefix, 0) }},
+		{"ArgusHeader", func(k *KeySet) { k.ArgusHeader = k.ArgusHeader[1:] }},
+		{"ArgusFooter", func(k *KeySet) { k.ArgusFooter = nil }},
+		{"ArgusPrefix", func(k *KeySet) { k.ArgusPrefix = nil }},
+		{"GorgonKey", func(k *KeySet) { k.GorgonKey = k.GorgonKey[:19] }},
+		{"GorgonVersion", func(k *KeySet) { k.GorgonVersion = "" }},
+		{"GorgonVersion", func(k *KeySet) { k.GorgonVersion = "zz" }},
+		{"LadonRounds", func(k *KeySet) { k.LadonRounds = 0 }},
+		{"LadonRounds", func(k *KeySet) { k.LadonRounds = ladonMaxRounds + 1 }},
+	}
+	for _, tt := range tests {
+		k := DefaultKeySet()
+		tt.change(k)
+		err := k.Validate()
+		if !errors.Is(err, ErrInvalidKeySet) || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("%s: error %v", tt.field, err)
+		}
+	}
+}
+
+func TestKeySetClone(t *testing.T) {
+	k := DefaultKeySet()
+	k.GorgonKey[0] ^= 0xff
+	k.ArgusHeader[0] ^= 0xff
+	if fresh := DefaultKeySet(); fresh.GorgonKey[0] == k.GorgonKey[0] || fresh.ArgusHeader[0] == k.ArgusHeader[0] {
+		t.Error("changing a copy changed the built-in keys")
+	}
+}
+
+func TestGorgonCustomKeys(t *testing.T) {
+	keys := customKeySet()
+	g := &Gorgon{Unix: 1700000000, Params: "device_id=1&aid=1233", Keys: keys}
+	out, err := g.GetValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := out["x-gorgon"]
+	if !strings.HasPrefix(header, keys.GorgonVersion) {
+		t.Errorf("%s lacks the custom version", header)
+	}
+
+	f, err := DecodeGorgonWithKeys(keys, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Unix != 1700000000 {
+		t.Errorf("decoded time %d", f.Unix)
+	}
+	if _, err := DecodeGorgon(header); err == nil {
+		t.Error("default keys decoded a custom header")
+	}
+
+	defaultOut, _ := (&Gorgon{Unix: 1700000000, Params: g.Params}).GetValue()
+	if defaultOut["x-gorgon"][12:] == header[12:] {
+		t.Error("custom GorgonKey had no effect")
+	}
+}
+
+func TestGorgonInvalidKeys(t *testing.T) {
+	keys := DefaultKeySet()
+	keys.GorgonKey = keys.GorgonKey[:4]
+	g := &Gorgon{Unix: 1700000000, Params: "device_id=1", Keys: keys}
+	if _, err := g.GetValue(); err == nil || !strings.Contains(err.Error(), "GorgonKey") {
+		t.Errorf("GetValue error %v", err)
+	}
+}
+
+func TestLadonCustomKeys(t *testing.T) {
+	keys := customKeySet()
+	header, err := LadonEncryptWithKeys(keys, 1700000000, 1611921764, 1233, []byte{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := DecodeLadonWithKeys(keys, header, 1233)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Plaintext != "1700000000-1611921764-1233" {
+		t.Errorf("plaintext %q", f.Plaintext)
+	}
+	if f, err := DecodeLadon(header, 1233); err == nil && f.Plaintext == "1700000000-1611921764-1233" {
+		t.Error("default rounds decoded a custom header")
+	}
+}
+
+func TestArgusCustomKeys(t *testing.T) {
+	keys := customKeySet()
+	bean := map[int]any{1: uint64(0x20200929) << 1, 5: "7300000000000000001", 20: "none"}
+	header, err := EncryptWithKeys(keys, bean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := DecryptWithKeys(keys, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := pb.GetUtf8(5); id != "7300000000000000001" {
+		t.Errorf("field 5 %q", id)
+	}
+	if _, err := Decrypt(header); err == nil {
+		t.Error("default keys decrypted a custom header")
+	}
+
+	bad := customKeySet()
+	bad.ArgusFooter = []byte("abc")
+	if _, err := EncryptWithKeys(bad, bean); err == nil {
+		t.Error("invalid ArgusFooter accepted")
+	}
+	if _, err := NewSchedule(bad); err == nil {
+		t.Error("NewSchedule accepted an invalid ArgusFooter")
+	}
+}
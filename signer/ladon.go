@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"math/bits"
 	"strconv"
+	"strings"
 )
 
 // ------------------------------------------------------------
@@ -61,9 +62,9 @@ func ror64(value uint64, count int) uint64 {
 // ------------------------------------------------------------
 
 // encryptLadonInput is equivalent to encrypt_ladon_input(hash_table, input_data).
-// hashTable is expected to contain at least (0x22) 8-byte entries (i.e. 0x22*8 bytes).
+// hashTable is expected to contain at least rounds (0x22 by default) 8-byte entries.
 // inputBlock must be exactly 16 bytes.
-func encryptLadonInput(hashTable []byte, inputBlock []byte) ([]byte, error) {
+func encryptLadonInput(hashTable []byte, inputBlock []byte, rounds int) ([]byte, error) {
 	if len(inputBlock) != 16 {
 		return nil, fmt.Errorf("encryptLadonInput: input block must be 16 bytes")
 	}
@@ -71,7 +72,7 @@ func encryptLadonInput(hashTable []byte, inputBlock []byte) ([]byte, error) {
 	data0 := binary.LittleEndian.Uint64(inputBlock[0:8])
 	data1 := binary.LittleEndian.Uint64(inputBlock[8:16])
 
-	for i := 0; i < 0x22; i++ {
+	for i := 0; i < rounds; i++ {
 		hash, err := getTypeData(hashTable, i, "uint64_t")
 		if err != nil {
 			return nil, err
@@ -90,16 +91,39 @@ func encryptLadonInput(hashTable []byte, inputBlock []byte) ([]byte, error) {
 	return out, nil
 }
 
+// decryptLadonInput reverses encryptLadonInput by running the rounds backwards.
+func decryptLadonInput(hashTable []byte, inputBlock []byte, rounds int) ([]byte, error) {
+	if len(inputBlock) != 16 {
+		return nil, fmt.Errorf("decryptLadonInput: input block must be 16 bytes")
+	}
+
+	data0 := binary.LittleEndian.Uint64(inputBlock[0:8])
+	data1 := binary.LittleEndian.Uint64(inputBlock[8:16])
+
+	for i := rounds - 1; i >= 0; i-- {
+		hash, err := getTypeData(hashTable, i, "uint64_t")
+		if err != nil {
+			return nil, err
+		}
+
+		data0 = bits.RotateLeft64(data0^data1, 0x3D)
+		data1 = bits.RotateLeft64((data1^hash)-data0, 8)
+	}
+
+	out := make([]byte, 16)
+	binary.LittleEndian.PutUint64(out[0:8], data0)
+	binary.LittleEndian.PutUint64(out[8:16], data1)
+	return out, nil
+}
+
 // ------------------------------------------------------------
 // Main encrypt_ladon (MD5-based key schedule + PKCS7 + block loop)
 // ------------------------------------------------------------
 
-// encryptLadon is equivalent to encrypt_ladon(md5hex: bytes, data: bytes, size: int) in Python.
+// ladonHashTable builds the round key table used by encrypt_ladon from md5hex.
 // NOTE: md5Hex here must be the same bytes as Python's `md5bytes(keygen).encode()`
 // i.e. 32 ASCII hex characters, not raw 16-byte MD5.
-func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
-	size := len(data)
-
+func ladonHashTable(md5Hex []byte, rounds int) ([]byte, error) {
 	// hash_table = bytearray(272 + 16)
 	hashTable := make([]byte, 272+16)
 
@@ -107,7 +131,7 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 	copy(hashTable[:32], md5Hex)
 
 	// temp = [first four uint64s of hash_table]
-	temp := make([]uint64, 0, 4+0x22)
+	temp := make([]uint64, 0, 4+rounds)
 	for i := 0; i < 4; i++ {
 		v, err := getTypeData(hashTable, i, "uint64_t")
 		if err != nil {
@@ -121,7 +145,7 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 	temp = temp[2:] // pop first two
 
 	// for i in range(0, 0x22)
-	for i := 0; i < 0x22; i++ {
+	for i := 0; i < rounds; i++ {
 		x9 := bufferB0
 		x8 := bufferB8
 
@@ -153,6 +177,22 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 		temp = temp[1:]
 	}
 
+	return hashTable, nil
+}
+
+// encryptLadon is equivalent to encrypt_ladon(md5hex: bytes, data: bytes, size: int) in Python.
+func encryptLadon(md5Hex []byte, data []byte, rounds int) ([]byte, error) {
+	hashTable, err := ladonHashTable(md5Hex, rounds)
+	if err != nil {
+		return nil, err
+	}
+	return encryptLadonWithTable(hashTable, data, rounds)
+}
+
+// encryptLadonWithTable is encryptLadon with a precomputed round table.
+func encryptLadonWithTable(hashTable []byte, data []byte, rounds int) ([]byte, error) {
+	size := len(data)
+
 	// padding_size(size)
 	paddingSize := func(size int) int {
 		mod := size % 16
@@ -189,7 +229,7 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 	output := make([]byte, newSize)
 	for i := 0; i < newSize/16; i++ {
 		block := input[i*16 : (i+1)*16]
-		enc, err := encryptLadonInput(hashTable, block)
+		enc, err := encryptLadonInput(hashTable, block, rounds)
 		if err != nil {
 			return nil, err
 		}
@@ -199,6 +239,39 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 	return output, nil
 }
 
+// decryptLadon reverses encryptLadon and strips the PKCS7 padding.
+func decryptLadon(md5Hex []byte, data []byte, rounds int) ([]byte, error) {
+	if len(data) == 0 || len(data)%16 != 0 {
+		return nil, fmt.Errorf("decryptLadon: ciphertext length %d is not a multiple of 16", len(data))
+	}
+
+	hashTable, err := ladonHashTable(md5Hex, rounds)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, len(data))
+	for i := 0; i < len(data)/16; i++ {
+		dec, err := decryptLadonInput(hashTable, data[i*16:(i+1)*16], rounds)
+		if err != nil {
+			return nil, err
+		}
+		copy(output[i*16:], dec)
+	}
+
+	padByte := int(output[len(output)-1])
+	if padByte == 0 || padByte > 16 {
+		return nil, fmt.Errorf("decryptLadon: invalid padding")
+	}
+	for _, b := range output[len(output)-padByte:] {
+		if int(b) != padByte {
+			return nil, fmt.Errorf("decryptLadon: invalid padding")
+		}
+	}
+
+	return output[:len(output)-padByte], nil
+}
+
 // ------------------------------------------------------------
 // Top-level ladon_encrypt API
 // ------------------------------------------------------------
@@ -206,30 +279,44 @@ func encryptLadon(md5Hex []byte, data []byte) ([]byte, error) {
 // LadonEncryptWithRandom is equivalent to:
 // ladon_encrypt(khronos, lc_id, aid, random_bytes=given)
 func LadonEncryptWithRandom(khronos, lcID, aid int64, randomBytes []byte) (string, error) {
+	return LadonEncryptWithKeys(nil, khronos, lcID, aid, randomBytes)
+}
+
+// LadonEncryptWithKeys is LadonEncryptWithRandom using the Ladon parameters of keys.
+func LadonEncryptWithKeys(keys *KeySet, khronos, lcID, aid int64, randomBytes []byte) (string, error) {
+	keys, err := keys.checked()
+	if err != nil {
+		return "", err
+	}
 
 	// data = f"{khronos}-{lc_id}-{aid}"
 	data := fmt.Sprintf("%d-%d-%d", khronos, lcID, aid)
 
-	// keygen = random_bytes + str(aid).encode()
-	keygen := make([]byte, 0, len(randomBytes)+16)
-	keygen = append(keygen, randomBytes...)
-	keygen = append(keygen, []byte(strconv.FormatInt(aid, 10))...)
-
-	// md5hex = md5bytes(keygen)  (this is hex string)
-	md5hex := md5Bytes(keygen)
-
 	// encrypt_ladon(md5hex.encode(), data.encode(), size)
-	cipher, err := encryptLadon([]byte(md5hex), []byte(data))
+	cipher, err := encryptLadon([]byte(ladonKeygen(randomBytes, aid)), []byte(data), keys.LadonRounds)
 	if err != nil {
 		return "", err
 	}
 
-	// output = random_bytes + cipher, then base64
+	return ladonOutput(randomBytes, cipher), nil
+}
+
+// ladonKeygen returns md5bytes(random_bytes + str(aid).encode()), the hex
+// string the round table is derived from.
+func ladonKeygen(randomBytes []byte, aid int64) string {
+	keygen := make([]byte, 0, len(randomBytes)+16)
+	keygen = append(keygen, randomBytes...)
+	keygen = append(keygen, []byte(strconv.FormatInt(aid, 10))...)
+	return md5Bytes(keygen)
+}
+
+// ladonOutput is base64(random_bytes[:4] + cipher).
+func ladonOutput(randomBytes, cipher []byte) string {
 	out := make([]byte, len(cipher)+4)
 	copy(out[:4], randomBytes)
 	copy(out[4:], cipher)
 
-	return base64.StdEncoding.EncodeToString(out), nil
+	return base64.StdEncoding.EncodeToString(out)
 }
 
 // LadonEncrypt is equivalent to Python ladon_encrypt(khronos, lc_id, aid)
@@ -243,9 +330,87 @@ func LadonEncrypt(khronos, lcID, aid int64) (string, error) {
 }
 
 // Ladon type with an Encrypt method, mirroring the Python class Ladon.encrypt.
-type Ladon struct{}
+// Keys overrides the built-in parameters when set.
+type Ladon struct {
+	Keys *KeySet
+}
 
 // Encrypt mirrors Ladon.encrypt(x_khronos, lc_id, aid) -> str.
-func (Ladon) Encrypt(xKhronos, lcID, aid int64) (string, error) {
-	return LadonEncrypt(xKhronos, lcID, aid)
+func (l Ladon) Encrypt(xKhronos, lcID, aid int64) (string, error) {
+	randBytes := make([]byte, 4)
+	if _, err := rand.Read(randBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return LadonEncryptWithKeys(l.Keys, xKhronos, lcID, aid, randBytes)
+}
+
+// Decrypt reverses Encrypt. aid must be the app id the header was made for,
+// since it is part of the key.
+func (l Ladon) Decrypt(xLadon string, aid int64) (string, error) {
+	fields, err := DecodeLadonWithKeys(l.Keys, xLadon, aid)
+	if err != nil {
+		return "", err
+	}
+	return fields.Plaintext, nil
+}
+
+// ------------------------------------------------------------
+// Decoding
+// ------------------------------------------------------------
+
+// LadonFields holds the plaintext recovered from an x-ladon header.
+type LadonFields struct {
+	Random    []byte
+	Plaintext string
+	Khronos   int64
+	LicenseID int64
+	AID       int64
+}
+
+// DecodeLadon decrypts an x-ladon header and splits its "khronos-lc_id-aid" plaintext.
+func DecodeLadon(xLadon string, aid int64) (*LadonFields, error) {
+	return DecodeLadonWithKeys(nil, xLadon, aid)
+}
+
+// DecodeLadonWithKeys is DecodeLadon using the Ladon parameters of keys.
+func DecodeLadonWithKeys(keys *KeySet, xLadon string, aid int64) (*LadonFields, error) {
+	keys, err := keys.checked()
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(xLadon)
+	if err != nil {
+		return nil, fmt.Errorf("ladon: %w", err)
+	}
+	if len(raw) < 4+16 {
+		return nil, fmt.Errorf("ladon: header too short")
+	}
+
+	randomBytes := raw[:4]
+
+	plain, err := decryptLadon([]byte(ladonKeygen(randomBytes, aid)), raw[4:], keys.LadonRounds)
+	if err != nil {
+		return nil, fmt.Errorf("ladon: %w (wrong aid?)", err)
+	}
+
+	fields := &LadonFields{
+		Random:    append([]byte(nil), randomBytes...),
+		Plaintext: string(plain),
+	}
+
+	parts := strings.Split(fields.Plaintext, "-")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ladon: unexpected plaintext %q", fields.Plaintext)
+	}
+	nums := []*int64{&fields.Khronos, &fields.LicenseID, &fields.AID}
+	for i, p := range parts {
+		v, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ladon: unexpected plaintext %q", fields.Plaintext)
+		}
+		*nums[i] = v
+	}
+
+	return fields, nil
 }
@@ -0,0 +1,34 @@
+package signer
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// FuzzLadonRoundTrip encrypts a khronos-license-aid triple and decodes it
+// again with the same aid.
+func FuzzLadonRoundTrip(f *testing.F) {
+	f.Fuzz(func(t *testing.T, khronos, licenseID, aid int64, random uint32) {
+		// The plaintext is dash-separated, so only non-negative values are
+		// representable; the app never sends others.
+		if khronos < 0 || licenseID < 0 || aid < 0 {
+			t.Skip()
+		}
+		randomBytes := binary.LittleEndian.AppendUint32(nil, random)
+
+		header, err := LadonEncryptWithRandom(khronos, licenseID, aid, randomBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields, err := DecodeLadon(header, aid)
+		if err != nil {
+			t.Fatalf("%s: %v", header, err)
+		}
+		if fields.Khronos != khronos || fields.LicenseID != licenseID || fields.AID != aid {
+			t.Errorf("decoded %d-%d-%d, want %d-%d-%d", fields.Khronos, fields.LicenseID, fields.AID, khronos, licenseID, aid)
+		}
+		if binary.LittleEndian.Uint32(fields.Random) != random {
+			t.Errorf("random %x, want %x", fields.Random, randomBytes)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// Platform — which app build is being signed for
+// ------------------------------------------------------------
+
+// Platform selects the app build a signature is made for. The zero value
+// means "not specified"; callers resolve it from the device_platform query
+// parameter and fall back to Android. Android is the only build whose
+// Argus constants have been captured; iOS device_platform values are
+// rejected rather than signed as Android.
+type Platform int
+
+const (
+	PlatformUnset Platform = iota
+	PlatformAndroid
+)
+
+func (p Platform) String() string {
+	switch p {
+	case PlatformUnset:
+		return "unset"
+	case PlatformAndroid:
+		return "android"
+	}
+	return fmt.Sprintf("Platform(%d)", int(p))
+}
+
+// ParsePlatform accepts the names used by String as well as the
+// device_platform query value "android". The iOS values are refused with
+// an error saying so.
+func ParsePlatform(s string) (Platform, error) {
+	switch strings.ToLower(s) {
+	case "", "unset":
+		return PlatformUnset, nil
+	case "android":
+		return PlatformAndroid, nil
+	case "ios", "iphone", "ipad":
+		return PlatformUnset, fmt.Errorf("platform: %q is not supported; only android has a verified profile", s)
+	}
+	return PlatformUnset, fmt.Errorf("unknown platform %q", s)
+}
+
+func (p Platform) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON accepts either a platform name or its numeric value.
+func (p *Platform) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		if n < int(PlatformUnset) || n > int(PlatformAndroid) {
+			return fmt.Errorf("unknown platform %d", n)
+		}
+		*p = Platform(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return p.UnmarshalText([]byte(s))
+}
+
+func (p *Platform) UnmarshalText(b []byte) error {
+	v, err := ParsePlatform(string(b))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// ------------------------------------------------------------
+// PlatformProfile — per-platform defaults and Argus constants
+// ------------------------------------------------------------
+
+// PlatformProfile is the complete set of values that differ between app
+// builds: the defaults applied to unset config fields and the constant
+// Argus fields.
+type PlatformProfile struct {
+	Platform Platform
+
+	// Defaults for the signing config.
+	AppID            int
+	LicenseID        int
+	SdkVersionString string
+	SdkVersionInt    int
+	VersionName      string
+
+	// DevicePlatform values the query may carry for this platform.
+	DevicePlatforms []string
+
+	EnvCode    []byte // field 10
+	PSKVersion string // field 20
+	CallType   int    // field 21
+}
+
+var platformProfiles = map[Platform]PlatformProfile{
+	PlatformAndroid: {
+		Platform:         PlatformAndroid,
+		AppID:            1233,
+		LicenseID:        1611921764,
+		SdkVersionString: "v05.00.06-ov-android",
+		SdkVersionInt:    167775296,
+		VersionName:      DefaultVersionName,
+		DevicePlatforms:  []string{"android"},
+		EnvCode:          make([]byte, 8),
+		PSKVersion:       "none",
+		CallType:         738,
+	},
+}
+
+// Profile returns the profile for p; PlatformUnset gets Android's. Values
+// outside the enum have none.
+func (p Platform) Profile() (PlatformProfile, error) {
+	if p == PlatformUnset {
+		p = PlatformAndroid
+	}
+	if prof, ok := platformProfiles[p]; ok {
+		return prof, nil
+	}
+	return PlatformProfile{}, fmt.Errorf("platform: no profile for %v", p)
+}
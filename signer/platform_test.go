@@ -0,0 +1,52 @@
+package signer
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlatformJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Platform
+		ok   bool
+	}{
+		{`"android"`, PlatformAndroid, true},
+		{`"iphone"`, 0, false},
+		{`"ios"`, 0, false},
+		{`""`, PlatformUnset, true},
+		{`0`, PlatformUnset, true},
+		{`1`, PlatformAndroid, true},
+		{`2`, 0, false},
+		{`-1`, 0, false},
+		{`"windows"`, 0, false},
+		{`true`, 0, false},
+	}
+
+	for _, tt := range tests {
+		var p Platform
+		err := json.Unmarshal([]byte(tt.in), &p)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error %v", tt.in, err)
+			continue
+		}
+		if tt.ok && p != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.in, p, tt.want)
+		}
+	}
+
+	out, err := json.Marshal(PlatformAndroid)
+	if err != nil || string(out) != `"android"` {
+		t.Errorf("Marshal: %s, %v", out, err)
+	}
+}
+
+func TestPlatformProfile(t *testing.T) {
+	unset, err := PlatformUnset.Profile()
+	if err != nil || unset.Platform != PlatformAndroid {
+		t.Errorf("unset profile %v, %v; want android", unset.Platform, err)
+	}
+	if _, err := Platform(9).Profile(); err == nil {
+		t.Error("unknown platform has a profile")
+	}
+}
@@ -1,8 +1,10 @@
 package signer
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"strings"
 )
 
 // ------------------------------------------------------------
@@ -65,6 +67,14 @@ type ProtoField struct {
 	Type     ProtoFieldType
 	IntVal   uint64
 	BytesVal []byte
+
+	// raw is the exact encoding the field was parsed from, key included,
+	// and parsed a copy of the values decoded from it. ToBytes writes raw
+	// back while the field still holds those values, so decode → encode is
+	// lossless even for non-minimal varints. Fields built by Set and Insert
+	// have neither.
+	raw    []byte
+	parsed *ProtoField
 }
 
 func (pf *ProtoField) IsAscii() bool {
@@ -188,9 +198,11 @@ func (w *ProtoWriter) WriteInt64(v uint64) {
 	w.write(buf[:])
 }
 
+// WriteVarint writes v in base-128. The Python original masked v to 32 bits
+// and wrote a final group of exactly 0x80 as 0x00; values unaffected by
+// either bug encode the same as before.
 func (w *ProtoWriter) WriteVarint(v uint64) {
-	v &= 0xFFFFFFFF // match Python masking
-	for v > 0x80 {
+	for v >= 0x80 {
 		w.writeByte(byte(v&0x7F) | 0x80)
 		v >>= 7
 	}
@@ -212,6 +224,10 @@ func (w *ProtoWriter) Bytes() []byte {
 
 type ProtoBuf struct {
 	Fields []*ProtoField
+
+	// trailer holds whatever followed a zero field key when parsing, so it
+	// survives re-encoding.
+	trailer []byte
 }
 
 func NewProtoBufFromBytes(data []byte) (*ProtoBuf, error) {
@@ -224,16 +240,25 @@ func NewProtoBufFromBytes(data []byte) (*ProtoBuf, error) {
 	return pb, nil
 }
 
-func (pb *ProtoBuf) parseBytes(data []byte) error {
+func (pb *ProtoBuf) parseBytes(data []byte) (err error) {
 	r := NewProtoReader(data)
 
+	// The reader panics on truncated input; surface that as a ProtoError.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &ProtoError{Msg: fmt.Sprint(rec)}
+		}
+	}()
+
 	for r.remain(1) {
+		start := r.pos
 		key := r.ReadVarint()
 		ftype := ProtoFieldType(key & 7)
 		idx := int(key >> 3)
-		fmt.Printf("[Go] Parsing field idx=%d type=%s\n", idx, ftype)
+		// fmt.Printf("[Go] Parsing field idx=%d type=%s\n", idx, ftype)
 
 		if idx == 0 {
+			pb.trailer = append([]byte(nil), data[start:]...)
 			break
 		}
 
@@ -254,14 +279,61 @@ func (pb *ProtoBuf) parseBytes(data []byte) error {
 			val := r.ReadString()
 			// fmt.Printf("[Go] Parsed STRING val=%x\n", val)
 			pb.Put(&ProtoField{Idx: idx, Type: ftype, BytesVal: val})
+		case TypeGroupStart:
+			val := r.skipGroup(idx)
+			pb.Put(&ProtoField{Idx: idx, Type: ftype, BytesVal: val})
 		default:
 			return &ProtoError{Msg: "unexpected protobuf field type"}
 		}
+
+		f := pb.Fields[len(pb.Fields)-1]
+		f.raw = append([]byte(nil), data[start:r.pos]...)
+		f.parsed = &ProtoField{Idx: f.Idx, Type: f.Type, IntVal: f.IntVal, BytesVal: bytes.Clone(f.BytesVal)}
 	}
 
 	return nil
 }
 
+// unchanged reports whether f still holds the values it was parsed from,
+// so its raw bytes can be written back. Fields edited through the exported
+// struct members fail the check and are re-encoded.
+func (f *ProtoField) unchanged() bool {
+	p := f.parsed
+	if p == nil {
+		return false
+	}
+	return p.Idx == f.Idx && p.Type == f.Type && p.IntVal == f.IntVal && bytes.Equal(p.BytesVal, f.BytesVal)
+}
+
+// skipGroup consumes a group up to and including its matching end key and
+// returns the bytes in between.
+func (r *ProtoReader) skipGroup(idx int) []byte {
+	start := r.pos
+	for {
+		end := r.pos
+		key := r.ReadVarint()
+		switch ProtoFieldType(key & 7) {
+		case TypeVarint:
+			r.ReadVarint()
+		case TypeInt64:
+			r.read(8)
+		case TypeString:
+			r.ReadString()
+		case TypeInt32:
+			r.read(4)
+		case TypeGroupStart:
+			r.skipGroup(int(key >> 3))
+		case TypeGroupEnd:
+			if int(key>>3) != idx {
+				panic("group end does not match start")
+			}
+			return r.data[start:end]
+		default:
+			panic("unexpected protobuf field type in group")
+		}
+	}
+}
+
 // ------------------------------------------------------------
 // ProtoBuf → bytes
 // ------------------------------------------------------------
@@ -270,6 +342,11 @@ func (pb *ProtoBuf) ToBytes() ([]byte, error) {
 	w := NewProtoWriter()
 
 	for _, f := range pb.Fields {
+		if f.unchanged() {
+			w.write(f.raw)
+			continue
+		}
+
 		key := (uint64(f.Idx) << 3) | uint64(f.Type)
 		// fmt.Printf("[Go] Writing field idx=%d type=%s key=%d\n", f.Idx, f.Type, key)
 		w.WriteVarint(key)
@@ -287,14 +364,30 @@ func (pb *ProtoBuf) ToBytes() ([]byte, error) {
 		case TypeString:
 			// fmt.Printf("[Go] Write STRING val=%x\n", f.BytesVal)
 			w.WriteString(f.BytesVal)
+		case TypeGroupStart:
+			w.write(f.BytesVal)
+			w.WriteVarint((uint64(f.Idx) << 3) | uint64(TypeGroupEnd))
 		default:
 			return nil, &ProtoError{Msg: "unexpected field type in encoder"}
 		}
 	}
 
+	w.write(pb.trailer)
 	return w.Bytes(), nil
 }
 
+// String renders one field per line, in wire order.
+func (pb *ProtoBuf) String() string {
+	var sb strings.Builder
+	for i, f := range pb.Fields {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(f.String())
+	}
+	return sb.String()
+}
+
 // ------------------------------------------------------------
 // Field getters / setters
 // ------------------------------------------------------------
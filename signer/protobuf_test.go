@@ -0,0 +1,184 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// roundTrips are encodings that decode → encode must reproduce byte for byte.
+var roundTrips = []struct {
+	name string
+	hex  string
+}{
+	{"empty", ""},
+	{"varint", "089601"},
+	{"non-minimal varint", "08968100"},
+	{"non-minimal zero", "088000"},
+	{"64-bit varint", "08ffffffffffffffffff01"},
+	{"fixed32", "0d04030201"},
+	{"fixed64", "110807060504030201"},
+	{"string", "1a03616263"},
+	{"empty string", "1a00"},
+	{"unknown field", "0801980601a0060a"},
+	{"repeated field", "080108020803"},
+	{"group", "2308012b10012c2418ff01"},
+	{"empty group", "2324"},
+	{"trailing bytes", "080100ffee"},
+	{"trailing zero key", "0801101000"},
+	{"non-minimal key", "8800011002"},
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestProtoBufRoundTrip(t *testing.T) {
+	for _, tt := range roundTrips {
+		t.Run(tt.name, func(t *testing.T) {
+			pb, err := NewProtoBufFromBytes(mustHex(t, tt.hex))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, p := range map[string]*ProtoBuf{"parsed": pb, "clone": pb.Clone()} {
+				got, err := p.ToBytes()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hex.EncodeToString(got) != tt.hex {
+					t.Errorf("%s:\ngot  %x\nwant %s", name, got, tt.hex)
+				}
+			}
+		})
+	}
+}
+
+func TestProtoBufEdits(t *testing.T) {
+	// Field 1 is a non-minimal varint, field 2 a string and field 3 a group.
+	const in = "08968100120361626323080124"
+
+	tests := []struct {
+		name string
+		edit func(*testing.T, *ProtoBuf)
+		want string
+	}{
+		{
+			name: "set re-encodes only the edited field",
+			edit: func(t *testing.T, pb *ProtoBuf) {
+				if err := pb.Set(2, "xy"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "089681001202787923080124",
+		},
+		{
+			name: "insert",
+			edit: func(t *testing.T, pb *ProtoBuf) {
+				if err := pb.Insert(1, 7, 5); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "089681003805120361626323080124",
+		},
+		{
+			name: "delete",
+			edit: func(t *testing.T, pb *ProtoBuf) { pb.Delete(2) },
+			want: "0896810023080124",
+		},
+		{
+			name: "IntVal assigned directly",
+			edit: func(t *testing.T, pb *ProtoBuf) { pb.Get(1).IntVal = 1 },
+			want: "0801120361626323080124",
+		},
+		{
+			name: "BytesVal changed in place",
+			edit: func(t *testing.T, pb *ProtoBuf) { pb.Get(2).BytesVal[0] = 'x' },
+			want: "08968100120378626323080124",
+		},
+		{
+			name: "value set back to the parsed one",
+			edit: func(t *testing.T, pb *ProtoBuf) {
+				f := pb.Get(1)
+				f.IntVal++
+				f.IntVal--
+			},
+			want: in,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pb, err := NewProtoBufFromBytes(mustHex(t, in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			orig := pb.Clone()
+			tt.edit(t, pb)
+			got, err := pb.ToBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("got  %x\nwant %s", got, tt.want)
+			}
+
+			// The clone taken before the edit is unaffected.
+			if b, _ := orig.ToBytes(); hex.EncodeToString(b) != in {
+				t.Errorf("clone changed: %x", b)
+			}
+		})
+	}
+}
+
+func TestProtoBufTruncated(t *testing.T) {
+	for _, s := range []string{"08", "0896", "1a05616263", "0d0102", "2308", "230801"} {
+		if _, err := NewProtoBufFromBytes(mustHex(t, s)); err == nil {
+			t.Errorf("%s parsed", s)
+		}
+	}
+}
+
+// FuzzProtoBufRoundTrip checks that anything the parser accepts is written
+// back byte for byte, and that re-encoding every field from its values
+// parses back to the same values.
+func FuzzProtoBufRoundTrip(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pb, err := NewProtoBufFromBytes(data)
+		if err != nil {
+			return
+		}
+		out, err := pb.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("round trip:\ngot  %x\nwant %x", out, data)
+		}
+
+		for _, field := range pb.Fields {
+			field.parsed = nil
+		}
+		out, err = pb.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := NewProtoBufFromBytes(out)
+		if err != nil {
+			t.Fatalf("re-encoded %x does not parse: %v", out, err)
+		}
+		if len(again.Fields) != len(pb.Fields) {
+			t.Fatalf("re-encoded %x has %d fields, want %d", out, len(again.Fields), len(pb.Fields))
+		}
+		for i, want := range pb.Fields {
+			got := again.Fields[i]
+			if got.Idx != want.Idx || got.Type != want.Type || got.IntVal != want.IntVal || !bytes.Equal(got.BytesVal, want.BytesVal) {
+				t.Errorf("field %d: got %v, want %v", i, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package signer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// Descriptor-driven decoding
+// ------------------------------------------------------------
+
+// DecodedField is one wire field interpreted through a descriptor.
+// Desc is nil for fields the descriptor does not know, in which case Value
+// holds the raw wire value. Message is set for message fields; repeated
+// packed scalars produce one DecodedField per element.
+type DecodedField struct {
+	Number  int
+	Wire    ProtoFieldType
+	Desc    *FieldDescriptor
+	Value   any
+	Message *DecodedMessage
+	Err     error
+}
+
+// Name returns the descriptor name, or the field number when unknown.
+func (f *DecodedField) Name() string {
+	if f.Desc == nil {
+		return strconv.Itoa(f.Number)
+	}
+	return f.Desc.Name
+}
+
+// TypeName returns the declared type, or the wire type when unknown.
+func (f *DecodedField) TypeName() string {
+	if f.Desc == nil {
+		return f.Wire.String()
+	}
+	name := f.Desc.Type
+	switch {
+	case f.Desc.Message != nil:
+		name = f.Desc.Message.Name
+	case f.Desc.Enum != nil:
+		name = f.Desc.Enum.Name
+	}
+	if f.Desc.Repeated {
+		return "repeated " + name
+	}
+	return name
+}
+
+// DecodedMessage is a ProtoBuf interpreted through a MessageDescriptor.
+type DecodedMessage struct {
+	Desc   *MessageDescriptor
+	Fields []*DecodedField
+}
+
+// DecodeWith interprets pb through desc. Fields whose wire type does not
+// fit the declared type are kept with Err set rather than failing the
+// whole message, since captured payloads are exactly where schemas drift.
+func (pb *ProtoBuf) DecodeWith(desc *MessageDescriptor) (*DecodedMessage, error) {
+	if desc == nil {
+		return nil, &ProtoError{Msg: "nil descriptor"}
+	}
+	msg := &DecodedMessage{Desc: desc}
+	for _, f := range pb.Fields {
+		fd := desc.Field(f.Idx)
+		if fd == nil {
+			msg.Fields = append(msg.Fields, &DecodedField{Number: f.Idx, Wire: f.Type, Value: rawValue(f)})
+			continue
+		}
+		msg.Fields = append(msg.Fields, decodeField(f, fd)...)
+	}
+	return msg, nil
+}
+
+// FormatWith renders pb through desc, one field per line, with nested
+// messages indented.
+func (pb *ProtoBuf) FormatWith(desc *MessageDescriptor) (string, error) {
+	msg, err := pb.DecodeWith(desc)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	msg.format(&sb, 0)
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+func (m *DecodedMessage) format(sb *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, f := range m.Fields {
+		fmt.Fprintf(sb, "%s%s = %d (%s)", indent, f.Name(), f.Number, f.TypeName())
+		switch {
+		case f.Err != nil:
+			fmt.Fprintf(sb, ": %s [%v]\n", FormatValue(f.Value), f.Err)
+		case f.Message != nil:
+			sb.WriteString(" {\n")
+			f.Message.format(sb, depth+1)
+			sb.WriteString(indent + "}\n")
+		default:
+			fmt.Fprintf(sb, ": %s\n", FormatValue(f.Value))
+		}
+	}
+}
+
+// FormatValue renders a decoded value: strings quoted, non-printable bytes
+// as h"hex", everything else with %v.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		if isASCII(v) {
+			return strconv.Quote(string(v))
+		}
+		return `h"` + hex.EncodeToString(v) + `"`
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func isASCII(b []byte) bool {
+	return (&ProtoField{BytesVal: b}).IsAscii()
+}
+
+func rawValue(f *ProtoField) any {
+	if f.Type == TypeString {
+		return f.BytesVal
+	}
+	return f.IntVal
+}
+
+// wireTypeOf returns the wire type a scalar is encoded with.
+func wireTypeOf(fd *FieldDescriptor) ProtoFieldType {
+	switch {
+	case fd.Message != nil:
+		return TypeString
+	case fd.Enum != nil:
+		return TypeVarint
+	}
+	switch fd.Type {
+	case "string", "bytes":
+		return TypeString
+	case "fixed32", "sfixed32", "float":
+		return TypeInt32
+	case "fixed64", "sfixed64", "double":
+		return TypeInt64
+	default:
+		return TypeVarint
+	}
+}
+
+func decodeField(f *ProtoField, fd *FieldDescriptor) []*DecodedField {
+	want := wireTypeOf(fd)
+
+	// Repeated numeric fields may be packed into a single length-delimited
+	// field.
+	if fd.Repeated && want != TypeString && f.Type == TypeString {
+		out, err := unpack(f, fd, want)
+		if err != nil {
+			return []*DecodedField{{Number: f.Idx, Wire: f.Type, Desc: fd, Value: f.BytesVal, Err: err}}
+		}
+		return out
+	}
+
+	if f.Type != want {
+		err := fmt.Errorf("wire type %s, want %s", f.Type, want)
+		return []*DecodedField{{Number: f.Idx, Wire: f.Type, Desc: fd, Value: rawValue(f), Err: err}}
+	}
+
+	out := &DecodedField{Number: f.Idx, Wire: f.Type, Desc: fd}
+	if fd.Message != nil {
+		sub, err := NewProtoBufFromBytes(f.BytesVal)
+		if err != nil {
+			out.Value, out.Err = f.BytesVal, err
+			return []*DecodedField{out}
+		}
+		out.Message, _ = sub.DecodeWith(fd.Message)
+		return []*DecodedField{out}
+	}
+	out.Value = scalarValue(fd, f.IntVal, f.BytesVal)
+	return []*DecodedField{out}
+}
+
+func unpack(f *ProtoField, fd *FieldDescriptor, wire ProtoFieldType) (out []*DecodedField, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, &ProtoError{Msg: fmt.Sprint("packed: ", r)}
+		}
+	}()
+	r := NewProtoReader(f.BytesVal)
+	for r.remain(1) {
+		var v uint64
+		switch wire {
+		case TypeInt32:
+			v = uint64(r.ReadInt32())
+		case TypeInt64:
+			v = r.ReadInt64()
+		default:
+			v = r.ReadVarint()
+		}
+		out = append(out, &DecodedField{Number: f.Idx, Wire: wire, Desc: fd, Value: scalarValue(fd, v, nil)})
+	}
+	return out, nil
+}
+
+// scalarValue converts a wire value into the Go value of the declared type.
+func scalarValue(fd *FieldDescriptor, v uint64, b []byte) any {
+	if fd.Enum != nil {
+		if name, ok := fd.Enum.Values[int32(v)]; ok {
+			return name
+		}
+		return int32(v)
+	}
+	switch fd.Type {
+	case "int32", "sfixed32":
+		return int32(v)
+	case "int64", "sfixed64":
+		return int64(v)
+	case "uint32", "fixed32":
+		return uint32(v)
+	case "uint64", "fixed64":
+		return v
+	case "sint32":
+		return int32(uint32(v)>>1) ^ -int32(v&1)
+	case "sint64":
+		return int64(v>>1) ^ -int64(v&1)
+	case "bool":
+		return v != 0
+	case "float":
+		return math.Float32frombits(uint32(v))
+	case "double":
+		return math.Float64frombits(v)
+	case "string":
+		return string(b)
+	default:
+		return b
+	}
+}
@@ -0,0 +1,120 @@
+package signer
+
+import (
+	"sort"
+	"strconv"
+)
+
+// ------------------------------------------------------------
+// Field-by-field comparison of decoded messages
+// ------------------------------------------------------------
+
+// DiffKind says how two decoded fields differ.
+type DiffKind int
+
+const (
+	// DiffExtra is a field present only in got.
+	DiffExtra DiffKind = iota
+	// DiffMissing is a field present only in want.
+	DiffMissing
+	// DiffType is a field whose wire type, or fit with the descriptor,
+	// differs between the two sides.
+	DiffType
+	// DiffValue is a field of the same type with a different value.
+	DiffValue
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffExtra:
+		return "extra"
+	case DiffMissing:
+		return "missing"
+	case DiffType:
+		return "type"
+	case DiffValue:
+		return "value"
+	}
+	return "DiffKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// FieldDiff is one difference found by DiffDecoded. Path names the field
+// by its dotted descriptor names, with "[i]" appended to the second and
+// later occurrences of a repeated field. Got or Want is nil for
+// DiffExtra and DiffMissing respectively.
+type FieldDiff struct {
+	Kind DiffKind
+	Path string
+	Got  *DecodedField
+	Want *DecodedField
+}
+
+// DiffDecoded compares got against want field by field, descending into
+// nested messages, and returns the differences ordered by field number.
+// Repeated fields are paired by position.
+func DiffDecoded(got, want *DecodedMessage) []FieldDiff {
+	var out []FieldDiff
+	diffDecoded(&out, "", got, want)
+	return out
+}
+
+func diffDecoded(out *[]FieldDiff, prefix string, got, want *DecodedMessage) {
+	g, w := byNumber(got), byNumber(want)
+
+	numbers := make([]int, 0, len(g)+len(w))
+	for n := range g {
+		numbers = append(numbers, n)
+	}
+	for n := range w {
+		if _, ok := g[n]; !ok {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	for _, n := range numbers {
+		gs, ws := g[n], w[n]
+		for i := 0; i < len(gs) || i < len(ws); i++ {
+			var gf, wf *DecodedField
+			if i < len(gs) {
+				gf = gs[i]
+			}
+			if i < len(ws) {
+				wf = ws[i]
+			}
+
+			named := wf
+			if named == nil {
+				named = gf
+			}
+			path := prefix + named.Name()
+			if i > 0 {
+				path += "[" + strconv.Itoa(i) + "]"
+			}
+
+			switch {
+			case wf == nil:
+				*out = append(*out, FieldDiff{Kind: DiffExtra, Path: path, Got: gf})
+			case gf == nil:
+				*out = append(*out, FieldDiff{Kind: DiffMissing, Path: path, Want: wf})
+			case gf.Wire != wf.Wire || (gf.Err == nil) != (wf.Err == nil) || (gf.Message == nil) != (wf.Message == nil):
+				*out = append(*out, FieldDiff{Kind: DiffType, Path: path, Got: gf, Want: wf})
+			case gf.Message != nil:
+				diffDecoded(out, path+".", gf.Message, wf.Message)
+			case FormatValue(gf.Value) != FormatValue(wf.Value):
+				*out = append(*out, FieldDiff{Kind: DiffValue, Path: path, Got: gf, Want: wf})
+			}
+		}
+	}
+}
+
+func byNumber(m *DecodedMessage) map[int][]*DecodedField {
+	out := map[int][]*DecodedField{}
+	if m == nil {
+		return out
+	}
+	for _, f := range m.Fields {
+		out[f.Number] = append(out[f.Number], f)
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package signer
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffProto = `
+	message M {
+		uint32 id = 1;
+		string name = 2;
+		Inner inner = 3;
+		repeated string tags = 4;
+		repeated uint32 nums = 5;
+		message Inner {
+			uint32 a = 1;
+			string b = 2;
+		}
+	}
+`
+
+func TestDiffDecoded(t *testing.T) {
+	fd, err := ParseProto(diffProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := fd.Message("M")
+
+	inner := func(a uint64, b string) []byte {
+		pb := &ProtoBuf{}
+		pb.PutVarint(1, a)
+		if b != "" {
+			pb.PutUtf8(2, b)
+		}
+		data, err := pb.ToBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	base := func(pb *ProtoBuf) {
+		pb.PutVarint(1, 7)
+		pb.PutUtf8(2, "n")
+		pb.PutBytes(3, inner(1, "x"))
+		pb.PutUtf8(4, "t0")
+		pb.PutUtf8(4, "t1")
+	}
+
+	tests := []struct {
+		name string
+		got  func(*ProtoBuf)
+		want func(*ProtoBuf)
+		diff []string // "kind path"
+	}{
+		{"equal", base, base, nil},
+		{"missing", func(pb *ProtoBuf) {
+			pb.PutVarint(1, 7)
+		}, func(pb *ProtoBuf) {
+			pb.PutVarint(1, 7)
+			pb.PutUtf8(2, "n")
+		}, []string{"missing name"}},
+		{"extra unknown field", func(pb *ProtoBuf) {
+			base(pb)
+			pb.PutVarint(9, 1)
+		}, base, []string{"extra 9"}},
+		{"wire type", func(pb *ProtoBuf) {
+			pb.PutUtf8(1, "7")
+		}, func(pb *ProtoBuf) {
+			pb.PutVarint(1, 7)
+		}, []string{"type id"}},
+		{"value", func(pb *ProtoBuf) {
+			pb.PutVarint(1, 8)
+		}, func(pb *ProtoBuf) {
+			pb.PutVarint(1, 7)
+		}, []string{"value id"}},
+		{"nested", func(pb *ProtoBuf) {
+			pb.PutBytes(3, inner(2, ""))
+		}, func(pb *ProtoBuf) {
+			pb.PutBytes(3, inner(1, "x"))
+		}, []string{"value inner.a", "missing inner.b"}},
+		{"nested message against scalar", func(pb *ProtoBuf) {
+			pb.PutVarint(3, 1)
+		}, func(pb *ProtoBuf) {
+			pb.PutBytes(3, inner(1, "x"))
+		}, []string{"type inner"}},
+		{"repeated by position", func(pb *ProtoBuf) {
+			pb.PutUtf8(4, "t0")
+			pb.PutUtf8(4, "changed")
+			pb.PutUtf8(4, "t2")
+		}, func(pb *ProtoBuf) {
+			pb.PutUtf8(4, "t0")
+			pb.PutUtf8(4, "t1")
+		}, []string{"value tags[1]", "extra tags[2]"}},
+		{"packed against unpacked", func(pb *ProtoBuf) {
+			pb.PutBytes(5, []byte{1, 2})
+		}, func(pb *ProtoBuf) {
+			pb.PutVarint(5, 1)
+			pb.PutVarint(5, 3)
+			pb.PutVarint(5, 4)
+		}, []string{"value nums[1]", "missing nums[2]"}},
+		{"ordered by field number", func(pb *ProtoBuf) {
+			pb.PutUtf8(4, "t0")
+			pb.PutVarint(1, 8)
+		}, func(pb *ProtoBuf) {
+			pb.PutUtf8(2, "n")
+			pb.PutVarint(1, 7)
+		}, []string{"value id", "missing name", "extra tags"}},
+	}
+	for _, tt := range tests {
+		got, want := &ProtoBuf{}, &ProtoBuf{}
+		tt.got(got)
+		tt.want(want)
+		gm, err := got.DecodeWith(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wm, err := want.DecodeWith(desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var diff []string
+		for _, d := range DiffDecoded(gm, wm) {
+			diff = append(diff, d.Kind.String()+" "+d.Path)
+			if (d.Got == nil) != (d.Kind == DiffMissing) || (d.Want == nil) != (d.Kind == DiffExtra) {
+				t.Errorf("%s: %s %s: Got %v, Want %v", tt.name, d.Kind, d.Path, d.Got, d.Want)
+			}
+		}
+		if strings.Join(diff, ", ") != strings.Join(tt.diff, ", ") {
+			t.Errorf("%s: diff %q, want %q", tt.name, diff, tt.diff)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package signer
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ------------------------------------------------------------
+// ProtoBuf editing
+// ------------------------------------------------------------
+
+// newField converts a Go value to a field using the same rules as the
+// Argus bean encoder. A *ProtoBuf value becomes an embedded message.
+func newField(idx int, value any) (*ProtoField, error) {
+	if sub, ok := value.(*ProtoBuf); ok {
+		b, err := sub.ToBytes()
+		if err != nil {
+			return nil, err
+		}
+		value = b
+	}
+
+	tmp := &ProtoBuf{}
+	if err := putBeanValue(tmp, idx, value, true); err != nil {
+		return nil, err
+	}
+	return tmp.Fields[0], nil
+}
+
+// Set replaces the first field idx in place with value and removes any
+// later fields with the same number. When idx is absent the field is
+// appended. Values follow the bean encoder: integers and bools become
+// varints, Fixed32/Fixed64 fixed-width, strings and []byte length-delimited.
+func (pb *ProtoBuf) Set(idx int, value any) error {
+	f, err := newField(idx, value)
+	if err != nil {
+		return err
+	}
+
+	for i, old := range pb.Fields {
+		if old.Idx != idx {
+			continue
+		}
+		pb.Fields[i] = f
+		kept := pb.Fields[:i+1]
+		for _, rest := range pb.Fields[i+1:] {
+			if rest.Idx != idx {
+				kept = append(kept, rest)
+			}
+		}
+		pb.Fields = kept
+		return nil
+	}
+
+	pb.Put(f)
+	return nil
+}
+
+// Delete removes every field idx and reports whether any was present.
+func (pb *ProtoBuf) Delete(idx int) bool {
+	kept := pb.Fields[:0]
+	for _, f := range pb.Fields {
+		if f.Idx != idx {
+			kept = append(kept, f)
+		}
+	}
+	removed := len(kept) != len(pb.Fields)
+	pb.Fields = kept
+	return removed
+}
+
+// Insert places a new field idx at position pos in wire order, shifting
+// later fields back. pos may equal len(pb.Fields) to append.
+func (pb *ProtoBuf) Insert(pos int, idx int, value any) error {
+	if pos < 0 || pos > len(pb.Fields) {
+		return &ProtoError{Msg: fmt.Sprintf("insert position %d out of range", pos)}
+	}
+	f, err := newField(idx, value)
+	if err != nil {
+		return err
+	}
+
+	pb.Fields = append(pb.Fields, nil)
+	copy(pb.Fields[pos+1:], pb.Fields[pos:])
+	pb.Fields[pos] = f
+	return nil
+}
+
+// Clone returns a deep copy that encodes to the same bytes.
+func (pb *ProtoBuf) Clone() *ProtoBuf {
+	c := &ProtoBuf{
+		Fields:  make([]*ProtoField, len(pb.Fields)),
+		trailer: bytes.Clone(pb.trailer),
+	}
+	for i, f := range pb.Fields {
+		cf := *f
+		cf.BytesVal = bytes.Clone(f.BytesVal)
+		cf.raw = bytes.Clone(f.raw)
+		if f.parsed != nil {
+			p := *f.parsed
+			p.BytesVal = bytes.Clone(p.BytesVal)
+			cf.parsed = &p
+		}
+		c.Fields[i] = &cf
+	}
+	return c
+}
+
+// Equal reports whether pb and other hold the same fields in the same
+// order. It compares values, not encodings.
+func (pb *ProtoBuf) Equal(other *ProtoBuf) bool {
+	if len(pb.Fields) != len(other.Fields) || !bytes.Equal(pb.trailer, other.trailer) {
+		return false
+	}
+	for i, f := range pb.Fields {
+		o := other.Fields[i]
+		if f.Idx != o.Idx || f.Type != o.Type || f.IntVal != o.IntVal || !bytes.Equal(f.BytesVal, o.BytesVal) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,465 @@
+package signer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------------
+// Descriptors
+// ------------------------------------------------------------
+
+// FileDescriptor is the result of parsing a .proto file.
+type FileDescriptor struct {
+	Package  string
+	Messages []*MessageDescriptor
+	Enums    []*EnumDescriptor
+}
+
+// MessageDescriptor describes one message type.
+type MessageDescriptor struct {
+	Name     string
+	FullName string
+	Fields   []*FieldDescriptor
+	Messages []*MessageDescriptor
+	Enums    []*EnumDescriptor
+
+	byNumber map[int]*FieldDescriptor
+}
+
+// FieldDescriptor describes one field. Type is the scalar type name
+// ("int32", "string", ...) or, for message and enum fields, the resolved
+// full name, in which case Message or Enum is set.
+type FieldDescriptor struct {
+	Name     string
+	Number   int
+	Type     string
+	Repeated bool
+	Message  *MessageDescriptor
+	Enum     *EnumDescriptor
+}
+
+// EnumDescriptor describes one enum type.
+type EnumDescriptor struct {
+	Name     string
+	FullName string
+	Values   map[int32]string
+}
+
+// Field returns the field with the given number, or nil.
+func (m *MessageDescriptor) Field(number int) *FieldDescriptor {
+	return m.byNumber[number]
+}
+
+// Message finds a message by full name, with or without the package prefix.
+func (fd *FileDescriptor) Message(name string) *MessageDescriptor {
+	name = strings.TrimPrefix(name, ".")
+	var find func([]*MessageDescriptor) *MessageDescriptor
+	find = func(ms []*MessageDescriptor) *MessageDescriptor {
+		for _, m := range ms {
+			if m.FullName == name || m.FullName == fd.Package+"."+name {
+				return m
+			}
+			if found := find(m.Messages); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return find(fd.Messages)
+}
+
+var scalarTypes = map[string]bool{
+	"double": true, "float": true,
+	"int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true,
+	"fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// ------------------------------------------------------------
+// Tokenizer
+// ------------------------------------------------------------
+
+type protoToken struct {
+	text string
+	line int
+}
+
+func tokenizeProto(src string) ([]protoToken, error) {
+	var toks []protoToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case unicode.IsSpace(rune(c)):
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("proto: line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("proto: line %d: unterminated string", line)
+			}
+			toks = append(toks, protoToken{src[i : j+1], line})
+			i = j + 1
+		case c == '_' || c == '.' || c == '-' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, protoToken{src[i:j], line})
+			i = j
+		default:
+			toks = append(toks, protoToken{string(c), line})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// ------------------------------------------------------------
+// Parser
+// ------------------------------------------------------------
+
+type protoParser struct {
+	toks []protoToken
+	pos  int
+	file *FileDescriptor
+}
+
+// ParseProto parses a .proto file. It understands the subset needed to
+// describe signing payloads: package, messages (nested), enums, scalar,
+// message and enum fields, and repeated. Imports, options and reserved
+// statements are skipped; maps, oneofs, groups and services are rejected.
+func ParseProto(src string) (*FileDescriptor, error) {
+	toks, err := tokenizeProto(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{toks: toks, file: &FileDescriptor{}}
+
+	for !p.done() {
+		switch tok := p.next(); tok.text {
+		case "syntax", "edition", "import", "option":
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		case "package":
+			p.file.Package = p.next().text
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "message":
+			m, err := p.parseMessage("")
+			if err != nil {
+				return nil, err
+			}
+			p.file.Messages = append(p.file.Messages, m)
+		case "enum":
+			e, err := p.parseEnum("")
+			if err != nil {
+				return nil, err
+			}
+			p.file.Enums = append(p.file.Enums, e)
+		case ";":
+		default:
+			return nil, p.errorf(tok, "unexpected %q", tok.text)
+		}
+	}
+
+	if err := p.resolve(); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+func (p *protoParser) done() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *protoParser) next() protoToken {
+	if p.done() {
+		return protoToken{line: -1}
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *protoParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.toks[p.pos].text
+}
+
+func (p *protoParser) errorf(tok protoToken, format string, args ...any) error {
+	if tok.line < 0 {
+		return fmt.Errorf("proto: unexpected end of file")
+	}
+	return fmt.Errorf("proto: line %d: %s", tok.line, fmt.Sprintf(format, args...))
+}
+
+func (p *protoParser) expect(text string) error {
+	if tok := p.next(); tok.text != text {
+		return p.errorf(tok, "expected %q, got %q", text, tok.text)
+	}
+	return nil
+}
+
+// skipStatement skips to the end of the current statement, including any
+// bracketed or braced option values.
+func (p *protoParser) skipStatement() error {
+	depth := 0
+	for {
+		tok := p.next()
+		switch tok.text {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+		if tok.line < 0 {
+			return p.errorf(tok, "")
+		}
+	}
+}
+
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (p *protoParser) parseMessage(scope string) (*MessageDescriptor, error) {
+	name := p.next()
+	m := &MessageDescriptor{Name: name.text, byNumber: map[int]*FieldDescriptor{}}
+	m.FullName = qualify(scope, name.text)
+	if scope == "" && p.file.Package != "" {
+		m.FullName = p.file.Package + "." + name.text
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			return m, nil
+		case ";":
+		case "option", "reserved", "extensions":
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		case "message":
+			sub, err := p.parseMessage(m.FullName)
+			if err != nil {
+				return nil, err
+			}
+			m.Messages = append(m.Messages, sub)
+		case "enum":
+			e, err := p.parseEnum(m.FullName)
+			if err != nil {
+				return nil, err
+			}
+			m.Enums = append(m.Enums, e)
+		case "map", "oneof", "group", "extend":
+			return nil, p.errorf(tok, "%s is not supported", tok.text)
+		default:
+			if tok.line < 0 {
+				return nil, p.errorf(tok, "")
+			}
+			p.pos--
+			f, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := m.byNumber[f.Number]; dup {
+				return nil, p.errorf(tok, "field number %d used twice in %s", f.Number, m.FullName)
+			}
+			m.Fields = append(m.Fields, f)
+			m.byNumber[f.Number] = f
+		}
+	}
+}
+
+func (p *protoParser) parseField() (*FieldDescriptor, error) {
+	f := &FieldDescriptor{}
+	tok := p.next()
+	switch tok.text {
+	case "repeated":
+		f.Repeated = true
+		tok = p.next()
+	case "optional", "required":
+		tok = p.next()
+	}
+	f.Type = tok.text
+
+	name := p.next()
+	f.Name = name.text
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	numTok := p.next()
+	n, err := strconv.Atoi(numTok.text)
+	if err != nil || n <= 0 {
+		return nil, p.errorf(numTok, "invalid field number %q", numTok.text)
+	}
+	f.Number = n
+
+	if p.peek() == "[" {
+		if err := p.skipStatement(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *protoParser) parseEnum(scope string) (*EnumDescriptor, error) {
+	name := p.next()
+	e := &EnumDescriptor{Name: name.text, Values: map[int32]string{}}
+	e.FullName = qualify(scope, name.text)
+	if scope == "" && p.file.Package != "" {
+		e.FullName = p.file.Package + "." + name.text
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			return e, nil
+		case ";":
+		case "option", "reserved":
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		default:
+			if tok.line < 0 {
+				return nil, p.errorf(tok, "")
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			numTok := p.next()
+			n, err := strconv.ParseInt(numTok.text, 0, 32)
+			if err != nil {
+				return nil, p.errorf(numTok, "invalid enum value %q", numTok.text)
+			}
+			if _, dup := e.Values[int32(n)]; !dup {
+				e.Values[int32(n)] = tok.text
+			}
+			if p.peek() == "[" {
+				if err := p.skipStatement(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// resolve links message and enum fields to their descriptors using
+// protobuf scoping: the innermost enclosing scope is searched first.
+func (p *protoParser) resolve() error {
+	messages := map[string]*MessageDescriptor{}
+	enums := map[string]*EnumDescriptor{}
+	var index func([]*MessageDescriptor, []*EnumDescriptor)
+	index = func(ms []*MessageDescriptor, es []*EnumDescriptor) {
+		for _, e := range es {
+			enums[e.FullName] = e
+		}
+		for _, m := range ms {
+			messages[m.FullName] = m
+			index(m.Messages, m.Enums)
+		}
+	}
+	index(p.file.Messages, p.file.Enums)
+
+	var walk func(*MessageDescriptor) error
+	walk = func(m *MessageDescriptor) error {
+		for _, f := range m.Fields {
+			if scalarTypes[f.Type] {
+				continue
+			}
+			full, ok := lookupType(m.FullName, f.Type, messages, enums)
+			if !ok {
+				return fmt.Errorf("proto: %s.%s: unknown type %q", m.FullName, f.Name, f.Type)
+			}
+			f.Type = full
+			f.Message = messages[full]
+			f.Enum = enums[full]
+		}
+		for _, sub := range m.Messages {
+			if err := walk(sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, m := range p.file.Messages {
+		if err := walk(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupType(scope, name string, messages map[string]*MessageDescriptor, enums map[string]*EnumDescriptor) (string, bool) {
+	exists := func(full string) bool {
+		return messages[full] != nil || enums[full] != nil
+	}
+	if strings.HasPrefix(name, ".") {
+		full := name[1:]
+		return full, exists(full)
+	}
+	for {
+		if full := qualify(scope, name); exists(full) {
+			return full, true
+		}
+		if scope == "" {
+			return "", false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package signer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizeProto(t *testing.T) {
+	src := "syntax = \"proto3\";\n" +
+		"// line comment; message X {}\n" +
+		"/* block\n comment */ message A{\n" +
+		"  string s='it\\'s'; int32 x_1 = -1;\n" +
+		"}"
+	toks, err := tokenizeProto(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		text string
+		line int
+	}{
+		{"syntax", 1}, {"=", 1}, {`"proto3"`, 1}, {";", 1},
+		{"message", 4}, {"A", 4}, {"{", 4},
+		{"string", 5}, {"s", 5}, {"=", 5}, {`'it\'s'`, 5}, {";", 5},
+		{"int32", 5}, {"x_1", 5}, {"=", 5}, {"-1", 5}, {";", 5},
+		{"}", 6},
+	}
+	if len(toks) != len(want) {
+		t.Fatalf("%d tokens, want %d: %v", len(toks), len(want), toks)
+	}
+	for i, w := range want {
+		if toks[i].text != w.text || toks[i].line != w.line {
+			t.Errorf("token %d = %q line %d, want %q line %d", i, toks[i].text, toks[i].line, w.text, w.line)
+		}
+	}
+
+	for _, bad := range []string{"/* open", `"open`, "'open\\'"} {
+		if _, err := tokenizeProto(bad); err == nil {
+			t.Errorf("%q tokenized", bad)
+		}
+	}
+}
+
+func TestParseProtoScoping(t *testing.T) {
+	fd, err := ParseProto(`
+		package p;
+		message Kind { int32 top = 1; }
+		enum Level { LOW = 0; }
+		message Outer {
+			message Kind { string mid = 1; }
+			message Inner {
+				message Kind { bool inner = 1; }
+				Kind a = 1;        // Outer.Inner.Kind
+				.p.Kind b = 2;     // the top-level Kind
+				Outer.Kind c = 3;  // resolved from the enclosing scopes
+				Level d = 4;       // top-level enum
+			}
+			Kind e = 1;            // Outer.Kind
+			Inner f = 2;
+			repeated Sibling g = 3;
+		}
+		message Sibling { Kind h = 1; }  // the top-level Kind
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		message string
+		field   int
+		want    string
+	}{
+		{"Outer.Inner", 1, "p.Outer.Inner.Kind"},
+		{"Outer.Inner", 2, "p.Kind"},
+		{"Outer.Inner", 3, "p.Outer.Kind"},
+		{"Outer.Inner", 4, "p.Level"},
+		{"Outer", 1, "p.Outer.Kind"},
+		{"Outer", 2, "p.Outer.Inner"},
+		{"Outer", 3, "p.Sibling"},
+		{"Sibling", 1, "p.Kind"},
+	}
+	for _, tt := range tests {
+		m := fd.Message(tt.message)
+		if m == nil {
+			t.Fatalf("message %s not found", tt.message)
+		}
+		f := m.Field(tt.field)
+		if f == nil || f.Type != tt.want {
+			t.Errorf("%s field %d: type %v, want %s", tt.message, tt.field, f, tt.want)
+			continue
+		}
+		if (f.Message == nil) == (f.Enum == nil) {
+			t.Errorf("%s field %d: exactly one of Message and Enum must be set", tt.message, tt.field)
+		}
+	}
+	if !fd.Message("Outer").Field(3).Repeated {
+		t.Error("repeated lost")
+	}
+}
+
+func TestParseProtoErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"message M {\n map<string, int32> m = 1;\n}", "line 2: map is not supported"},
+		{"message M {\n oneof o { int32 a = 1; }\n}", "line 2: oneof is not supported"},
+		{"message M {\n group G = 1 {}\n}", "line 2: group is not supported"},
+		{"message M {}\nextend M { int32 x = 2; }", `line 2: unexpected "extend"`},
+		{"message M { Missing m = 1; }", `M.m: unknown type "Missing"`},
+		{"message M { message N {} }\nmessage O { N n = 1; }", `O.n: unknown type "N"`},
+		{"message M { int32 a = 1; int32 b = 1; }", "field number 1 used twice"},
+		{"message M { int32 a = 0; }", "invalid field number"},
+		{"message M { int32 a = 1;", "unexpected end of file"},
+		{"service S {}", `unexpected "service"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseProto(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: error %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestArgusDescriptor(t *testing.T) {
+	m := ArgusDescriptor()
+	if m == nil || m.FullName != "ttsig.argus.Argus" {
+		t.Fatalf("descriptor %v", m)
+	}
+	for _, n := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 13, 14, 16, 20, 21, 25} {
+		if m.Field(n) == nil {
+			t.Errorf("field %d missing", n)
+		}
+	}
+	if e := m.Field(11).Enum; e == nil || e.FullName != "ttsig.argus.Argus.Platform" {
+		t.Errorf("field 11 enum %v", e)
+	}
+	if sub := m.Field(15).Message; sub == nil || sub.Field(1).Name != "signature_count" {
+		t.Errorf("field 15 message %v", sub)
+	}
+}
@@ -0,0 +1,197 @@
+package signer
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ------------------------------------------------------------
+// Query — ordered, encoding-preserving query string
+// ------------------------------------------------------------
+
+// QueryParam is one key=value pair. Key and Value are decoded; the raw
+// forms are what gets written back out.
+type QueryParam struct {
+	Key   string
+	Value string
+
+	rawKey   string
+	rawValue string
+	hasValue bool
+}
+
+// Query is a query string that keeps parameter order and the exact
+// percent-encoding it was parsed with. Gorgon and Argus hash the query
+// byte for byte, so String() is what both the URL and the signers must use.
+type Query struct {
+	params []QueryParam
+}
+
+// NewQuery returns an empty Query.
+func NewQuery() *Query {
+	return &Query{}
+}
+
+// ParseQuery splits raw on '&' without re-encoding anything. Like the
+// signers before it, it accepts any query the app may send: a key or value
+// with an invalid percent-escape (a bare '%', "%zz") keeps its raw text as
+// its decoded form instead of failing, so the error is currently always nil.
+func ParseQuery(raw string) (*Query, error) {
+	q := &Query{}
+	if raw == "" {
+		return q, nil
+	}
+
+	for _, part := range strings.Split(raw, "&") {
+		rawKey, rawValue, hasValue := strings.Cut(part, "=")
+		q.params = append(q.params, QueryParam{
+			Key:      queryUnescape(rawKey),
+			Value:    queryUnescape(rawValue),
+			rawKey:   rawKey,
+			rawValue: rawValue,
+			hasValue: hasValue,
+		})
+	}
+	return q, nil
+}
+
+// queryUnescape decodes s, or returns it unchanged when it is not valid
+// percent-encoding.
+func queryUnescape(s string) string {
+	if v, err := url.QueryUnescape(s); err == nil {
+		return v
+	}
+	return s
+}
+
+// appEscape percent-encodes s the way the app does: RFC 3986 unreserved
+// characters are kept, everything else (including space) becomes %XX.
+func appEscape(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0x0F])
+		}
+	}
+	return sb.String()
+}
+
+func newQueryParam(key, value string) QueryParam {
+	return QueryParam{
+		Key:      key,
+		Value:    value,
+		rawKey:   appEscape(key),
+		rawValue: appEscape(value),
+		hasValue: true,
+	}
+}
+
+// Add appends key=value, encoding both app-style.
+func (q *Query) Add(key, value string) {
+	q.params = append(q.params, newQueryParam(key, value))
+}
+
+// Set replaces the value of the first key in place and removes any later
+// duplicates, or appends the pair when key is absent.
+func (q *Query) Set(key, value string) {
+	for i := range q.params {
+		if q.params[i].Key == key {
+			q.params[i] = newQueryParam(key, value)
+			rest := q.params[:i+1]
+			for _, p := range q.params[i+1:] {
+				if p.Key != key {
+					rest = append(rest, p)
+				}
+			}
+			q.params = rest
+			return
+		}
+	}
+	q.Add(key, value)
+}
+
+// Del removes every pair with the given key.
+func (q *Query) Del(key string) {
+	kept := q.params[:0]
+	for _, p := range q.params {
+		if p.Key != key {
+			kept = append(kept, p)
+		}
+	}
+	q.params = kept
+}
+
+// Get returns the first value for key, or "".
+func (q *Query) Get(key string) string {
+	v, _ := q.Lookup(key)
+	return v
+}
+
+// Lookup returns the first value for key and whether it was present.
+func (q *Query) Lookup(key string) (string, bool) {
+	for _, p := range q.params {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Params returns the pairs in order.
+func (q *Query) Params() []QueryParam {
+	return append([]QueryParam(nil), q.params...)
+}
+
+// Len returns the number of pairs.
+func (q *Query) Len() int {
+	return len(q.params)
+}
+
+// String returns the canonical query string: the pairs in order, with
+// parsed pairs keeping their original encoding. This is what the signers hash.
+func (q *Query) String() string {
+	var sb strings.Builder
+	for i, p := range q.params {
+		if i > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(p.rawKey)
+		if p.hasValue {
+			sb.WriteByte('=')
+			sb.WriteString(p.rawValue)
+		}
+	}
+	return sb.String()
+}
+
+// ------------------------------------------------------------
+// Typed accessors for the parameters the signers care about
+// ------------------------------------------------------------
+
+func (q *Query) DeviceID() string       { return q.Get("device_id") }
+func (q *Query) IID() string            { return q.Get("iid") }
+func (q *Query) VersionName() string    { return q.Get("version_name") }
+func (q *Query) DevicePlatform() string { return q.Get("device_platform") }
+
+// AID returns the aid parameter, or 0 when it is absent.
+func (q *Query) AID() (int, error) {
+	v, ok := q.Lookup("aid")
+	if !ok || v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("query: invalid aid %q", v)
+	}
+	return n, nil
+}
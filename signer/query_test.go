@@ -0,0 +1,121 @@
+package signer
+
+import "testing"
+
+func TestParseQueryKeepsEncoding(t *testing.T) {
+	tests := []struct {
+		raw   string
+		key   string
+		value string
+	}{
+		{"a=b", "a", "b"},
+		{"q=hello%20world", "q", "hello world"},
+		{"q=hello+world", "q", "hello world"},
+		{"q=%e4%bd%a0", "q", "你"},
+		{"q=%E4%BD%A0", "q", "你"},
+		{"cb=a%2Cb%3Dc", "cb", "a,b=c"},
+		{"flag", "flag", ""},
+		{"empty=", "empty", ""},
+		{"%61id=1", "aid", "1"},
+		// Invalid escapes keep their raw text rather than failing.
+		{"q=100%", "q", "100%"},
+		{"q=%zz", "q", "%zz"},
+		{"q%=1", "q%", "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			q, err := ParseQuery(tt.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := q.String(); got != tt.raw {
+				t.Errorf("String() = %q, want the input back", got)
+			}
+			v, ok := q.Lookup(tt.key)
+			if !ok || v != tt.value {
+				t.Errorf("Lookup(%q) = %q, %v; want %q", tt.key, v, ok, tt.value)
+			}
+		})
+	}
+}
+
+func TestQueryAddEscapes(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+	}{
+		{"a", "b", "a=b"},
+		{"q", "hello world", "q=hello%20world"},
+		{"q", "a+b", "q=a%2Bb"},
+		{"q", "-_.~", "q=-_.~"},
+		{"q", "a,b=c&d", "q=a%2Cb%3Dc%26d"},
+		{"q", "你", "q=%E4%BD%A0"},
+		{"k y", "", "k%20y="},
+	}
+
+	for _, tt := range tests {
+		q := NewQuery()
+		q.Add(tt.key, tt.value)
+		if got := q.String(); got != tt.want {
+			t.Errorf("Add(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+		p, _ := ParseQuery(tt.want)
+		if p.Get(tt.key) != tt.value {
+			t.Errorf("%q parses to %q, want %q", tt.want, p.Get(tt.key), tt.value)
+		}
+	}
+}
+
+func TestQueryOrder(t *testing.T) {
+	const raw = "device_id=1&aid=1233&ts=1&aid=1128&z=%7E"
+
+	tests := []struct {
+		name string
+		edit func(*Query)
+		want string
+	}{
+		{"unchanged", func(*Query) {}, raw},
+		{"add appends", func(q *Query) { q.Add("a", "1") }, raw + "&a=1"},
+		{"set keeps the first position and drops duplicates", func(q *Query) { q.Set("aid", "8") }, "device_id=1&aid=8&ts=1&z=%7E"},
+		{"set appends a new key", func(q *Query) { q.Set("new", "x y") }, raw + "&new=x%20y"},
+		{"set leaves other encodings alone", func(q *Query) { q.Set("ts", "2") }, "device_id=1&aid=1233&ts=2&aid=1128&z=%7E"},
+		{"del removes every pair", func(q *Query) { q.Del("aid") }, "device_id=1&ts=1&z=%7E"},
+		{"del of a missing key", func(q *Query) { q.Del("nope") }, raw},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(q)
+			if got := q.String(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryAccessors(t *testing.T) {
+	q, _ := ParseQuery("device_id=7300000000000000001&aid=1233&aid=1128&device_platform=android&version_name=39.6.3&iid=9")
+	if q.DeviceID() != "7300000000000000001" || q.IID() != "9" || q.DevicePlatform() != "android" || q.VersionName() != "39.6.3" {
+		t.Errorf("accessors: %q %q %q %q", q.DeviceID(), q.IID(), q.DevicePlatform(), q.VersionName())
+	}
+	if aid, err := q.AID(); err != nil || aid != 1233 {
+		t.Errorf("AID() = %d, %v; want the first aid", aid, err)
+	}
+	if q.Len() != 6 {
+		t.Errorf("Len() = %d", q.Len())
+	}
+
+	q, _ = ParseQuery("aid=abc")
+	if _, err := q.AID(); err == nil {
+		t.Error("non-numeric aid accepted")
+	}
+	q, _ = ParseQuery("aid=")
+	if aid, err := q.AID(); err != nil || aid != 0 {
+		t.Errorf("empty aid: %d, %v", aid, err)
+	}
+}
@@ -0,0 +1,270 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/Skill/ttsig/crypto"
+)
+
+// Schedule holds everything derived from a KeySet that does not depend on
+// the request: the expanded SIMON key and the Argus AES cipher and IV.
+// Building one per KeySet and reusing it avoids redoing that work for every
+// signature. The Ladon round table depends on each request's random bytes,
+// so it is not part of the schedule. It is safe for concurrent use.
+type Schedule struct {
+	keys  *KeySet
+	simon *crypto.SimonKey
+	aes   cipher.Block
+	iv    [16]byte
+}
+
+// NewSchedule validates keys (nil means the defaults) and precomputes its
+// key schedules. keys is copied, so later changes to it have no effect.
+func NewSchedule(keys *KeySet) (*Schedule, error) {
+	keys, err := keys.checked()
+	if err != nil {
+		return nil, err
+	}
+	return newSchedule(keys.Clone())
+}
+
+// newSchedule is NewSchedule for keys that are already validated and will
+// not change.
+func newSchedule(keys *KeySet) (*Schedule, error) {
+	key := md5.Sum(keys.ArgusSignKey[:16])
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Schedule{
+		keys:  keys,
+		simon: crypto.ExpandSimonKey(keys.simonKey()),
+		aes:   block,
+		iv:    md5.Sum(keys.ArgusSignKey[16:]),
+	}, nil
+}
+
+// Keys returns the key set the schedule was built from.
+func (s *Schedule) Keys() *KeySet {
+	return s.keys
+}
+
+// ------------------------------------------------------------
+// Argus
+// ------------------------------------------------------------
+
+// ArgusTimings splits the time spent producing one x-argus value.
+type ArgusTimings struct {
+	Protobuf time.Duration // building and encoding the bean
+	Simon    time.Duration // SIMON over the padded protobuf
+	AES      time.Duration // wrapping, AES-CBC and base64
+}
+
+// ArgusTrace holds the intermediate values of one x-argus computation,
+// for checking other implementations layer by layer.
+type ArgusTrace struct {
+	Protobuf []byte // serialized bean, before padding
+	Simon    []byte // SIMON output over the padded bean
+	Wrapped  []byte // XOR-prefixed, scrambled and wrapped: the AES input, unpadded
+	AES      []byte // AES-CBC ciphertext, before the outer prefix
+}
+
+// Sign is the package-level Sign using the schedule's keys; p.Keys is
+// ignored.
+func (s *Schedule) Sign(p ArgusParams) (string, error) {
+	out, _, err := s.SignTimed(p)
+	return out, err
+}
+
+// SignTimed is Sign, also reporting how long each stage took.
+func (s *Schedule) SignTimed(p ArgusParams) (string, ArgusTimings, error) {
+	var t ArgusTimings
+	out, err := s.SignTraced(p, &t, nil)
+	return out, t, err
+}
+
+// SignTraced is Sign, also filling in the stage timings of t and the
+// intermediate values of tr when they are not nil. With t nil the clock is
+// not read.
+func (s *Schedule) SignTraced(p ArgusParams, t *ArgusTimings, tr *ArgusTrace) (string, error) {
+	var start time.Time
+	if t != nil {
+		start = time.Now()
+	}
+
+	bean, err := p.Bean()
+	if err != nil {
+		return "", err
+	}
+	raw, err := EncodeBean(bean, p.Strict)
+	if t != nil {
+		t.Protobuf = time.Since(start)
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.encryptArgusTimed(raw, t, tr), nil
+}
+
+// Decrypt is DecryptWithKeys using the schedule's keys.
+func (s *Schedule) Decrypt(xArgus string) (*ProtoBuf, error) {
+	raw, err := s.decryptArgus(xArgus)
+	if err != nil {
+		return nil, err
+	}
+	return NewProtoBufFromBytes(raw)
+}
+
+// encryptArgus runs every layer after protobuf encoding: SIMON, the XOR
+// prefix, the header/footer wrapper, AES-CBC and the outer prefix.
+func (s *Schedule) encryptArgus(raw []byte) string {
+	return s.encryptArgusTimed(raw, nil, nil)
+}
+
+// encryptArgusTimed is encryptArgus, filling in the Simon and AES timings
+// of t and the intermediate values of tr when they are not nil.
+func (s *Schedule) encryptArgusTimed(raw []byte, t *ArgusTimings, tr *ArgusTrace) string {
+	keys := s.keys
+	var start, mid time.Time
+	if t != nil {
+		start = time.Now()
+	}
+
+	//Padded protobuf string
+	protobuf := pkcs7Pad(raw, aes.BlockSize)
+	n := len(protobuf)
+
+	encPB := make([]byte, n)
+
+	for i := 0; i < n/16; i++ {
+		pt0 := binary.LittleEndian.Uint64(protobuf[i*16 : i*16+8])
+		pt1 := binary.LittleEndian.Uint64(protobuf[i*16+8 : i*16+16])
+
+		ct := s.simon.Encrypt([2]uint64{pt0, pt1}, 0)
+
+		binary.LittleEndian.PutUint64(encPB[i*16:], ct[0])
+		binary.LittleEndian.PutUint64(encPB[i*16+8:], ct[1])
+	}
+
+	if t != nil {
+		mid = time.Now()
+	}
+
+	// prefix + encrypt_enc_pb + wrap with header/footer
+	buf := append(append([]byte{}, keys.ArgusXorPrefix...), encPB...)
+	buf = encryptEncPB(buf, n+8)
+
+	buf = append(append([]byte{}, keys.ArgusHeader...), buf...)
+	buf = append(buf, keys.ArgusFooter...)
+
+	// AES-CBC
+	ciphertext := aesCBCEncrypt(s.aes, s.iv[:], buf)
+
+	final := append(append([]byte{}, keys.ArgusPrefix...), ciphertext...)
+	out := base64.StdEncoding.EncodeToString(final)
+
+	if t != nil {
+		t.Simon = mid.Sub(start)
+		t.AES = time.Since(mid)
+	}
+	if tr != nil {
+		tr.Protobuf = raw
+		tr.Simon = encPB
+		tr.Wrapped = buf
+		tr.AES = ciphertext
+	}
+	return out
+}
+
+// decryptArgus undoes encryptArgus and returns the serialized bean.
+func (s *Schedule) decryptArgus(xArgus string) ([]byte, error) {
+	keys := s.keys
+
+	raw, err := base64.StdEncoding.DecodeString(xArgus)
+	if err != nil {
+		return nil, fmt.Errorf("argus: %w", err)
+	}
+	if !bytes.HasPrefix(raw, keys.ArgusPrefix) {
+		return nil, fmt.Errorf("argus: missing %x prefix", keys.ArgusPrefix)
+	}
+
+	buf, err := aesCBCDecrypt(s.aes, s.iv[:], raw[len(keys.ArgusPrefix):])
+	if err != nil {
+		return nil, fmt.Errorf("argus: %w", err)
+	}
+
+	if !bytes.HasPrefix(buf, keys.ArgusHeader) || !bytes.HasSuffix(buf, keys.ArgusFooter) {
+		return nil, fmt.Errorf("argus: unexpected wrapper bytes")
+	}
+	buf = buf[len(keys.ArgusHeader) : len(buf)-len(keys.ArgusFooter)]
+
+	buf = decryptEncPB(buf)
+	if !bytes.HasPrefix(buf, keys.ArgusXorPrefix) {
+		return nil, fmt.Errorf("argus: unexpected xor prefix")
+	}
+	encPB := buf[len(keys.ArgusXorPrefix):]
+	if len(encPB)%16 != 0 {
+		return nil, fmt.Errorf("argus: SIMON payload length %d is not a multiple of 16", len(encPB))
+	}
+
+	protobuf := make([]byte, len(encPB))
+
+	for i := 0; i < len(encPB)/16; i++ {
+		ct0 := binary.LittleEndian.Uint64(encPB[i*16 : i*16+8])
+		ct1 := binary.LittleEndian.Uint64(encPB[i*16+8 : i*16+16])
+
+		pt := s.simon.Decrypt([2]uint64{ct0, ct1}, 0)
+
+		binary.LittleEndian.PutUint64(protobuf[i*16:], pt[0])
+		binary.LittleEndian.PutUint64(protobuf[i*16+8:], pt[1])
+	}
+
+	out, err := pkcs7Unpad(protobuf, aes.BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("argus: %w", err)
+	}
+	return out, nil
+}
+
+// ------------------------------------------------------------
+// Ladon
+// ------------------------------------------------------------
+
+// LadonTrace holds the intermediate values of one x-ladon computation.
+type LadonTrace struct {
+	Plaintext  string // "khronos-lc_id-aid"
+	Key        string // hex MD5 of the random bytes and aid
+	Ciphertext []byte // before the random bytes are prepended
+}
+
+// LadonEncrypt is LadonEncryptWithKeys using the schedule's keys.
+func (s *Schedule) LadonEncrypt(khronos, lcID, aid int64, randomBytes []byte) (string, error) {
+	return s.LadonEncryptTraced(khronos, lcID, aid, randomBytes, nil)
+}
+
+// LadonEncryptTraced is LadonEncrypt, also recording the intermediate
+// values in tr when it is not nil.
+func (s *Schedule) LadonEncryptTraced(khronos, lcID, aid int64, randomBytes []byte, tr *LadonTrace) (string, error) {
+	key := ladonKeygen(randomBytes, aid)
+	table, err := ladonHashTable([]byte(key), s.keys.LadonRounds)
+	if err != nil {
+		return "", err
+	}
+
+	data := fmt.Sprintf("%d-%d-%d", khronos, lcID, aid)
+	cipher, err := encryptLadonWithTable(table, []byte(data), s.keys.LadonRounds)
+	if err != nil {
+		return "", err
+	}
+	if tr != nil {
+		*tr = LadonTrace{Plaintext: data, Key: key, Ciphertext: cipher}
+	}
+	return ladonOutput(randomBytes, cipher), nil
+}
@@ -0,0 +1,106 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+func TestWithConfigMerges(t *testing.T) {
+	obs := &recordingObserver{}
+	s, err := New(
+		WithAppID(1128),
+		WithVersionName("30.1.0"),
+		WithObserver(obs),
+		WithStrict(),
+		WithConfig(SignConfig{LicenseID: 42, SdkVersionString: "v05.00.06-ov-android"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.base
+	if b.AppID != 1128 || b.VersionName != "30.1.0" || b.Observer != obs || !b.Strict {
+		t.Errorf("WithConfig dropped earlier options: %+v", b)
+	}
+	if b.LicenseID != 42 || b.SdkVersionString != "v05.00.06-ov-android" {
+		t.Errorf("WithConfig fields not applied: %+v", b)
+	}
+
+	// A later option still overrides a field the config set.
+	s, err = New(WithConfig(SignConfig{AppID: 1233, LicenseID: 42}), WithAppID(1128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.base.AppID != 1128 || s.base.LicenseID != 42 {
+		t.Errorf("AppID %d, LicenseID %d, want 1128 and 42", s.base.AppID, s.base.LicenseID)
+	}
+
+	if _, err := New(WithConfig(SignConfig{Platform: Platform(2)})); err == nil {
+		t.Error("WithConfig accepted a platform without a profile")
+	}
+}
+
+// TestSignerRandConcurrent signs in parallel from one WithRand stream of
+// counters and recovers each signature's 8 bytes from x-ladon and x-argus:
+// every call must get its own, unbroken 8 bytes.
+func TestSignerRandConcurrent(t *testing.T) {
+	const n = 64
+	stream := make([]byte, 8*n)
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint32(stream[8*i:], uint32(i))
+		binary.LittleEndian.PutUint32(stream[8*i+4:], uint32(i))
+	}
+	s, err := New(WithRand(bytes.NewReader(stream)), WithClock(func() time.Time { return time.Unix(1700000000, 0) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := make([]SignedHeaders, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			headers[i], errs[i] = s.Sign(context.Background(), SignConfig{
+				RawRequestParameters: fmt.Sprintf("device_id=7300000000000000001&aid=1233&cursor=%d", i),
+			})
+		}()
+	}
+	wg.Wait()
+
+	seen := map[uint32]bool{}
+	for i, h := range headers {
+		if errs[i] != nil {
+			t.Fatalf("request %d: %v", i, errs[i])
+		}
+		ladon, err := base64.StdEncoding.DecodeString(h["x-ladon"])
+		if err != nil || len(ladon) < 4 {
+			t.Fatalf("request %d: x-ladon %q", i, h["x-ladon"])
+		}
+		pb, err := signer.DecryptWithKeys(nil, h["x-argus"])
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		argusRand, err := pb.GetInt(3)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+
+		first, second := binary.BigEndian.Uint32(ladon), uint32(argusRand)
+		if first != second || first >= n {
+			t.Errorf("request %d: random bytes %08x %08x are not one 8-byte draw", i, first, second)
+		}
+		if seen[first] {
+			t.Errorf("request %d: draw %d used twice", i, first)
+		}
+		seen[first] = true
+	}
+}
@@ -0,0 +1,100 @@
+package ttsig
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SkewTracker learns the offset between the local clock and the server's
+// from the Date headers of responses, and applies it to signing times. The
+// server rejects signatures whose khronos is too far from its own clock, so
+// hosts with a drifting clock should sign through a tracker.
+//
+// A SkewTracker is safe for concurrent use.
+type SkewTracker struct {
+	mu     sync.RWMutex
+	offset time.Duration
+	seen   bool
+
+	// now is the local clock; nil means time.Now.
+	now func() time.Time
+}
+
+// skewSmoothing is the weight of a new sample in the running offset. Date
+// headers only have one-second resolution, so single samples are noisy.
+const skewSmoothing = 0.25
+
+// NewSkewTracker returns a tracker with no offset.
+func NewSkewTracker() *SkewTracker {
+	return &SkewTracker{}
+}
+
+func (s *SkewTracker) localNow() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Offset returns the learned server-minus-local offset.
+func (s *SkewTracker) Offset() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.offset
+}
+
+// Now returns the local time corrected by the learned offset.
+func (s *SkewTracker) Now() time.Time {
+	return s.localNow().Add(s.Offset())
+}
+
+// Observe records a server Date header value seen at local time local.
+// Headers that do not parse are ignored.
+func (s *SkewTracker) Observe(date string, local time.Time) {
+	server, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+	// Date is truncated to the second; on average the true time is half a
+	// second later.
+	sample := server.Add(500 * time.Millisecond).Sub(local)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.seen {
+		s.offset = sample
+		s.seen = true
+		return
+	}
+	s.offset += time.Duration(float64(sample-s.offset) * skewSmoothing)
+}
+
+// Transport wraps base (http.DefaultTransport when nil) so every response
+// Date header updates the tracker. The local time of a sample is the
+// midpoint of the round trip.
+func (s *SkewTracker) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &skewTransport{base: base, tracker: s}
+}
+
+type skewTransport struct {
+	base    http.RoundTripper
+	tracker *SkewTracker
+}
+
+func (t *skewTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := t.tracker.localNow()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	end := t.tracker.localNow()
+
+	if date := resp.Header.Get("Date"); date != "" {
+		t.tracker.Observe(date, start.Add(end.Sub(start)/2))
+	}
+	return resp, nil
+}
@@ -0,0 +1,129 @@
+package ttsig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dateServer answers every request with the Date header it was last
+// given; "" suppresses the header.
+type dateServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	date string
+}
+
+func newDateServer(t *testing.T) *dateServer {
+	s := &dateServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		date := s.date
+		s.mu.Unlock()
+		if date == "" {
+			w.Header()["Date"] = nil
+		} else {
+			w.Header().Set("Date", date)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *dateServer) get(t *testing.T, client *http.Client, date string) {
+	t.Helper()
+	s.mu.Lock()
+	s.date = date
+	s.mu.Unlock()
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestSkewTrackerTransport(t *testing.T) {
+	local := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	tracker := NewSkewTracker()
+	tracker.now = func() time.Time { return local }
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	srv := newDateServer(t)
+
+	// No samples yet.
+	srv.get(t, client, "")
+	if got := tracker.Offset(); got != 0 {
+		t.Fatalf("offset %v without a Date header", got)
+	}
+
+	// The first sample is taken as is, plus half a second for the
+	// truncated Date.
+	srv.get(t, client, local.Add(10*time.Second).Format(http.TimeFormat))
+	if got, want := tracker.Offset(), 10500*time.Millisecond; got != want {
+		t.Fatalf("first sample: offset %v, want %v", got, want)
+	}
+	if got, want := tracker.Now(), local.Add(10500*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Now %v, want %v", got, want)
+	}
+
+	// Later ones move the offset a quarter of the way.
+	srv.get(t, client, local.Add(-30*time.Second).Format(http.TimeFormat))
+	if got, want := tracker.Offset(), 500*time.Millisecond; got != want {
+		t.Fatalf("second sample: offset %v, want %v", got, want)
+	}
+	srv.get(t, client, local.Add(4*time.Second).Format(http.TimeFormat))
+	if got, want := tracker.Offset(), 1500*time.Millisecond; got != want {
+		t.Fatalf("third sample: offset %v, want %v", got, want)
+	}
+
+	// Missing and invalid headers leave it alone.
+	for _, date := range []string{"", "yesterday", "Tue, 14 Nov 2023 99:13:20 GMT"} {
+		srv.get(t, client, date)
+		if got := tracker.Offset(); got != 1500*time.Millisecond {
+			t.Errorf("Date %q changed the offset to %v", date, got)
+		}
+	}
+}
+
+func TestSkewTrackerRoundTripMidpoint(t *testing.T) {
+	// Each clock read advances 2s, so the round trip spans start..start+2s
+	// and the sample is taken at its midpoint.
+	start := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	var mu sync.Mutex
+	next := start
+	tracker := NewSkewTracker()
+	tracker.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		t := next
+		next = next.Add(2 * time.Second)
+		return t
+	}
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	srv := newDateServer(t)
+
+	srv.get(t, client, start.Add(time.Minute).Format(http.TimeFormat))
+	if got, want := tracker.Offset(), time.Minute+500*time.Millisecond-time.Second; got != want {
+		t.Errorf("offset %v, want %v", got, want)
+	}
+}
+
+func TestSkewTrackerSigningTime(t *testing.T) {
+	local := time.Unix(1700000000, 0)
+	tracker := NewSkewTracker()
+	tracker.Observe(local.Add(-time.Hour).UTC().Format(http.TimeFormat), local)
+
+	// The signer applies the offset to its own clock.
+	headers, err := SignRequest(SignConfig{
+		RawRequestParameters: "device_id=7300000000000000001&aid=1233",
+		Clock:                func() time.Time { return local },
+		Skew:                 tracker,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1699996400"; headers["x-khronos"] != want {
+		t.Errorf("x-khronos %s, want %s", headers["x-khronos"], want)
+	}
+}
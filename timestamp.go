@@ -0,0 +1,34 @@
+package ttsig
+
+import (
+	"math"
+	"time"
+)
+
+// signingTime resolves the time a config should be signed at. This is the
+// only place the clock is read.
+func (c *SignConfig) signingTime() time.Time {
+	switch {
+	case !c.Timestamp.IsZero():
+		return c.Timestamp
+	case c.UnixTimestamp != 0:
+		return time.UnixMicro(int64(math.Round(c.UnixTimestamp * 1e6)))
+	case c.Skew != nil:
+		return c.localNow().Add(c.Skew.Offset())
+	}
+	return c.localNow()
+}
+
+func (c *SignConfig) localNow() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
+}
+
+// signTime derives x-khronos (whole seconds) and x-ss-req-ticket
+// (milliseconds) from a single instant. Both are truncated, so khronos is
+// always ticket/1000.
+func signTime(t time.Time) (khronos, ticket int64) {
+	return t.Unix(), t.UnixMilli()
+}
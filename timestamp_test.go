@@ -0,0 +1,67 @@
+package ttsig
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignTime(t *testing.T) {
+	tests := []struct {
+		t       time.Time
+		khronos int64
+		ticket  int64
+	}{
+		{time.UnixMilli(1700000000123), 1700000000, 1700000000123},
+		{time.Unix(1700000000, 0), 1700000000, 1700000000000},
+		// Rounding the ticket here would give 1700000001000 and push it a
+		// full second away from khronos.
+		{time.Unix(1700000000, 999_600_000), 1700000000, 1700000000999},
+		{time.Unix(1700000000, 999_999_999), 1700000000, 1700000000999},
+	}
+	for _, tt := range tests {
+		khronos, ticket := signTime(tt.t)
+		if khronos != tt.khronos || ticket != tt.ticket {
+			t.Errorf("signTime(%v) = %d, %d; want %d, %d", tt.t, khronos, ticket, tt.khronos, tt.ticket)
+		}
+		if ticket/1000 != khronos {
+			t.Errorf("signTime(%v): ticket %d disagrees with khronos %d", tt.t, ticket, khronos)
+		}
+	}
+}
+
+func TestSigningTimeUnixTimestamp(t *testing.T) {
+	// The deprecated float timestamp must not lose a millisecond to
+	// binary representation.
+	cfg := SignConfig{UnixTimestamp: 1700000000.123}
+	if _, ticket := signTime(cfg.signingTime()); ticket != 1700000000123 {
+		t.Errorf("ticket %d, want 1700000000123", ticket)
+	}
+}
+
+func TestVerifyAtEndOfSecond(t *testing.T) {
+	for _, ts := range []time.Time{time.Unix(1700000000, 999_600_000), time.Unix(1700000000, 999_500_000)} {
+		headers, err := SignRequest(SignConfig{RawRequestParameters: "device_id=1&aid=1233", Timestamp: ts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if headers["x-khronos"] != "1700000000" || headers["x-ss-req-ticket"] != "1700000000999" {
+			t.Errorf("%v: x-khronos %s, x-ss-req-ticket %s", ts, headers["x-khronos"], headers["x-ss-req-ticket"])
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "https://api.example.com/feed?device_id=1&aid=1233", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rep, err := Verify(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rep.OK() {
+			t.Errorf("%v: own signature rejected:\n%s", ts, rep)
+		}
+	}
+}
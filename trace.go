@@ -0,0 +1,31 @@
+package ttsig
+
+import "github.com/Skill/ttsig/signer"
+
+// SignTrace is the output of SignRequest together with the intermediate
+// value of every layer, for test vectors that let other implementations
+// find the first step where they diverge.
+type SignTrace struct {
+	// Config is the resolved config that was signed.
+	Config SignConfig
+
+	Headers SignedHeaders
+
+	// GorgonBase is the string of query, body and cookie MD5s Gorgon is
+	// computed from.
+	GorgonBase string
+
+	Ladon signer.LadonTrace
+	Argus signer.ArgusTrace
+}
+
+// TraceRequest is SignRequest, also returning the intermediate values.
+func TraceRequest(cfg SignConfig) (*SignTrace, error) {
+	tr := &SignTrace{}
+	headers, err := signRequest(cfg, nil, tr)
+	if err != nil {
+		return nil, err
+	}
+	tr.Headers = headers
+	return tr, nil
+}
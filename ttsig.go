@@ -1,17 +1,21 @@
 package ttsig
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"context"
+	"crypto/rand"
 	"errors"
-	"math"
+	"io"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/Skill/ttsig/signer"
 )
 
+// DefaultAppID is the aid assumed when neither the config, the query nor
+// an x-argus bean names one: that of the Android profile, which supplies
+// every other default through Platform.Profile.
+const DefaultAppID = 1233
+
 type SignConfig struct {
 	RawRequestParameters string
 	RequestPayload       string
@@ -21,93 +25,261 @@ type SignConfig struct {
 	LicenseID            int
 	SdkVersionString     string
 	SdkVersionInt        int
-	Platform             int
-	UnixTimestamp        float64
+	Platform             Platform
+
+	// DeviceID and VersionName are taken from the query's device_id and
+	// version_name when empty. If set, they must agree with the query.
+	DeviceID    string
+	VersionName string
+
+	// Query, when set, supplies the query string instead of
+	// RawRequestParameters; use its String() for the request URL as well.
+	Query *Query `json:"-"`
+
+	// Timestamp is the signing time. When zero, the current time is used,
+	// corrected by Skew if one is set.
+	Timestamp time.Time
+
+	// Deprecated: use Timestamp. UnixTimestamp is only consulted when
+	// Timestamp is zero.
+	UnixTimestamp float64
+
+	// Skew, when set, corrects the local clock by the offset learned from
+	// server Date headers. It is ignored when a timestamp is given.
+	Skew *SkewTracker `json:"-"`
+
+	// Clock replaces time.Now as the local clock, e.g. in sandboxes whose
+	// host supplies the time. Skew still applies on top of it.
+	Clock func() time.Time `json:"-"`
+
+	// Body, when set, is used instead of RequestPayload. It allows binary
+	// and streamed bodies and is read only once.
+	Body *Body `json:"-"`
+
+	// Keys overrides the built-in algorithm constants. It is validated
+	// before use.
+	Keys *KeySet `json:"-"`
+
+	// Strict makes the Argus encoder reject values it cannot represent
+	// instead of leaving them out.
+	Strict bool
+
+	// Observer, when set, is told how long each signing stage took.
+	Observer Observer `json:"-"`
+
+	// Rand supplies the random bytes: 4 for x-ladon, then 4 for the Argus
+	// random field. When nil, crypto/rand is used. Replaying captured bytes
+	// makes both headers reproducible.
+	Rand io.Reader `json:"-"`
 }
 
 type SignedHeaders map[string]string
 
+var errNoQuery = errors.New("RawParams must not be empty unless DeviceID is set")
+
+// KeySet groups the algorithm constants; see signer.KeySet.
+type KeySet = signer.KeySet
+
+// DefaultKeySet returns a copy of the built-in keys, as a starting point
+// for a rotated set.
+func DefaultKeySet() *KeySet {
+	return signer.DefaultKeySet()
+}
+
+// defaultSigner backs SignRequest. Having no options, it adds nothing to
+// the config and builds key schedules for custom KeySets on every call.
+var defaultSigner = &Signer{}
+
+// SignRequest signs a single request. Clients signing many requests with
+// the same constants should keep a Signer from New instead.
 func SignRequest(signParams SignConfig) (SignedHeaders, error) {
-	unixSeconds := int64(signParams.UnixTimestamp)
-	unixMilliseconds := int64(math.Round(signParams.UnixTimestamp * 1000))
-	if signParams.AppID == 0 {
-		signParams.AppID = 1233
-	}
-	if signParams.LicenseID == 0 {
-		signParams.LicenseID = 1611921764
-	}
-	if signParams.SdkVersionString == "" {
-		signParams.SdkVersionString = "v05.00.06-ov-android"
-	}
-	if signParams.SdkVersionInt == 0 {
-		signParams.SdkVersionInt = 167775296
+	return defaultSigner.Sign(context.Background(), signParams)
+}
+
+// signRequest signs with the key schedules from schedules, or with freshly
+// built ones when it is nil, and records intermediate values in tr when it
+// is not nil.
+func signRequest(signParams SignConfig, schedules *scheduleCache, tr *SignTrace) (out SignedHeaders, err error) {
+	timer := stageTimer{signParams.Observer}
+	finish := timer.start(StageSign)
+	defer func() { finish(err) }()
+
+	unixSeconds, unixMilliseconds := signTime(signParams.signingTime())
+
+	done := timer.start(StageResolve)
+	signParams, err = signParams.Resolve()
+	if err != nil {
+		done(err)
+		return nil, err
 	}
-	if signParams.UnixTimestamp == 0 {
-		signParams.UnixTimestamp = float64(time.Now().UnixNano()) / 1e9
+	sched, err := schedules.get(signParams.Keys)
+	done(err)
+	if err != nil {
+		return nil, err
 	}
 
-	if signParams.RawRequestParameters == "" {
-		return nil, errors.New("RawParams must not be empty")
+	done = timer.start(StageBody)
+	body := signParams.Body
+	if body == nil {
+		body = NewBody([]byte(signParams.RequestPayload))
 	}
-
-	var xssStub string
-	if signParams.RequestPayload != "" {
-		xssStub = md5Upper(signParams.RequestPayload)
+	digest, err := body.Digest()
+	done(err)
+	if err != nil {
+		return nil, err
 	}
+	timer.body(digest.Length)
+	xssStub := digest.Stub()
+
+	done = timer.start(StageGorgon)
 
 	gorgonSigner := &signer.Gorgon{
 		Unix:    unixSeconds,
 		Params:  signParams.RawRequestParameters,
-		Data:    signParams.RequestPayload,
 		Cookies: signParams.Cookie,
+		Keys:    signParams.Keys,
+	}
+	if digest.Length > 0 {
+		gorgonSigner.DataMD5 = digest.md5Hex()
+	}
+
+	gorgon, err := gorgonSigner.GetValue()
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+	xGorgon := gorgon["x-gorgon"]
+	if tr != nil {
+		tr.Config = signParams
+		tr.GorgonBase = gorgonSigner.BaseString()
 	}
 
-	gorgonHeader := gorgonSigner.GetValue()
+	done = timer.start(StageLadon)
+	randSource := signParams.Rand
+	if randSource == nil {
+		randSource = rand.Reader
+	}
+	randBytes := make([]byte, 4)
+	if _, err := io.ReadFull(randSource, randBytes); err != nil {
+		done(err)
+		return nil, err
+	}
+
+	var ladonTrace *signer.LadonTrace
+	var argusTrace *signer.ArgusTrace
+	if tr != nil {
+		ladonTrace, argusTrace = &tr.Ladon, &tr.Argus
+	}
 
-	xLadon, err := (signer.Ladon{}).Encrypt(
+	xLadon, err := sched.LadonEncryptTraced(
 		unixSeconds,
 		int64(signParams.LicenseID),
 		int64(signParams.AppID),
+		randBytes,
+		ladonTrace,
 	)
-
+	done(err)
 	if err != nil {
 		return nil, err
 	}
 
-	xArgus, err := signer.GetSign(
-		signParams.RawRequestParameters,
-		xssStub,
-		unixSeconds,
-		signParams.AppID,
-		signParams.LicenseID,
-		signParams.Platform,
-		signParams.SecDeviceID,
-		signParams.SdkVersionString,
-		signParams.SdkVersionInt,
-	)
+	done = timer.start(StageArgus)
+	argusTimings := timer.argusTimings()
+	xArgus, err := sched.SignTraced(signer.ArgusParams{
+		Query:         signParams.RawRequestParameters,
+		BodyStub:      xssStub,
+		Timestamp:     unixSeconds,
+		AID:           signParams.AppID,
+		LicenseID:     signParams.LicenseID,
+		Platform:      signParams.Platform,
+		DeviceID:      signParams.DeviceID,
+		VersionName:   signParams.VersionName,
+		SecDeviceID:   signParams.SecDeviceID,
+		SdkVersion:    signParams.SdkVersionString,
+		SdkVersionInt: signParams.SdkVersionInt,
+		Keys:          signParams.Keys,
+		Strict:        signParams.Strict,
+		Rand:          randSource,
+	}, argusTimings, argusTrace)
+	timer.argus(argusTimings, err)
+	done(err)
 	if err != nil {
 		return nil, err
 	}
 
-	out := map[string]string{}
+	out = map[string]string{}
 
-	for k, v := range gorgonHeader {
-		out[k] = v
-	}
+	out["x-gorgon"] = xGorgon
 	out["x-khronos"] = strconv.FormatInt(unixSeconds, 10)
 	out["x-ss-req-ticket"] = strconv.FormatInt(unixMilliseconds, 10)
 	out["x-ladon"] = xLadon
 	out["x-argus"] = xArgus
 
-	if signParams.RequestPayload != "" {
-		out["content-length"] = strconv.Itoa(len(signParams.RequestPayload))
+	if digest.Length > 0 {
+		out["content-length"] = strconv.FormatInt(digest.Length, 10)
 		out["x-ss-stub"] = xssStub
 	}
+	if ct := body.ContentType(); ct != "" {
+		out["content-type"] = ct
+	}
 
 	return out, nil
 }
 
-func md5Upper(s string) string {
-	h := md5.Sum([]byte(s))
-	return strings.ToUpper(hex.EncodeToString(h[:]))
+// Resolve returns the config SignRequest actually signs with: the query
+// string canonicalized, fields the query carries filled in and checked,
+// and defaults applied.
+func (c SignConfig) Resolve() (SignConfig, error) {
+	if c.Query != nil {
+		canonical := c.Query.String()
+		if c.RawRequestParameters != "" && c.RawRequestParameters != canonical {
+			return c, errors.New("RawRequestParameters and Query disagree")
+		}
+		c.RawRequestParameters = canonical
+	}
+
+	// Without a query the device_id has to come from the config.
+	if c.RawRequestParameters == "" && c.DeviceID == "" {
+		return c, errNoQuery
+	}
+
+	if c.Keys != nil {
+		if err := c.Keys.Validate(); err != nil {
+			return c, err
+		}
+	}
+
+	query, err := ParseQuery(c.RawRequestParameters)
+	if err != nil {
+		return c, err
+	}
+	if err := c.resolveQuery(query); err != nil {
+		return c, err
+	}
+
+	if c.Platform == PlatformUnset {
+		c.Platform = PlatformAndroid
+	}
+	prof, err := c.Platform.Profile()
+	if err != nil {
+		return c, err
+	}
+
+	if c.AppID == 0 {
+		c.AppID = prof.AppID
+	}
+	if c.LicenseID == 0 {
+		c.LicenseID = prof.LicenseID
+	}
+	if c.SdkVersionString == "" {
+		c.SdkVersionString = prof.SdkVersionString
+	}
+	if c.SdkVersionInt == 0 {
+		c.SdkVersionInt = prof.SdkVersionInt
+	}
+	if c.VersionName == "" {
+		c.VersionName = prof.VersionName
+	}
+
+	return c, nil
 }
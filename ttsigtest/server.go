@@ -0,0 +1,270 @@
+// Package ttsigtest provides a local stand-in for the TikTok API that
+// checks the signature headers on every request. It is meant for
+// integration tests of clients built on ttsig.
+package ttsigtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+// DefaultMaxAge is how far x-khronos may be from the server clock before a
+// request is rejected as expired.
+const DefaultMaxAge = 5 * time.Minute
+
+// Error codes written in the status_code field of rejections.
+const (
+	CodeUnsigned         = 1001
+	CodeExpired          = 1002
+	CodeInvalidSignature = 1003
+)
+
+// Request is one request the server received, with the outcome of its
+// signature checks.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+
+	// Report is nil for unsigned requests.
+	Report *ttsig.Report
+
+	// Code and Reason are zero when the request was accepted.
+	Code   int
+	Reason string
+}
+
+// Accepted reports whether the request passed every check.
+func (r *Request) Accepted() bool {
+	return r.Code == 0
+}
+
+// Server is an httptest.Server that validates x-argus, x-ladon, x-gorgon,
+// x-khronos and x-ss-stub before handing requests to the registered
+// handlers. Rejected requests get a JSON error body and are not passed on.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	mux      *http.ServeMux
+	routes   int
+	requests []*Request
+	maxAge   time.Duration
+	clock    func() time.Time
+}
+
+// NewServer starts a server. Until handlers are registered every accepted
+// request gets {"status_code":0}.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a server that has not been started, so its
+// TLS or Config fields can be set first.
+func NewUnstartedServer() *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Handle registers h for pattern, using http.ServeMux pattern syntax
+// ("GET /aweme/v1/feed/").
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.Handle(pattern, h)
+	s.routes++
+}
+
+// HandleFunc registers f for pattern.
+func (s *Server) HandleFunc(pattern string, f func(http.ResponseWriter, *http.Request)) {
+	s.Handle(pattern, http.HandlerFunc(f))
+}
+
+// RespondJSON registers a handler that answers pattern with status and v
+// encoded as JSON.
+func (s *Server) RespondJSON(pattern string, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic("ttsigtest: RespondJSON: " + err.Error())
+	}
+	s.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+// Requests returns every request received so far, in order.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// LastRequest returns the most recent request, or nil.
+func (s *Server) LastRequest() *Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// Rejected returns the requests that failed a check.
+func (s *Server) Rejected() []*Request {
+	var out []*Request
+	for _, r := range s.Requests() {
+		if !r.Accepted() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// SetMaxAge overrides DefaultMaxAge. d <= 0 restores the default. It may
+// be called while requests are being served.
+func (s *Server) SetMaxAge(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAge = d
+}
+
+// SetClock replaces the server clock; nil restores time.Now. Tests move it
+// to exercise expiry and clock skew, also while requests are in flight.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = now
+}
+
+// Reset forgets the recorded requests; handlers stay registered.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// settings returns the clock reading and maximum age a request is
+// checked against.
+func (s *Server) settings() (now time.Time, maxAge time.Duration) {
+	s.mu.Lock()
+	clock, maxAge := s.clock, s.maxAge
+	s.mu.Unlock()
+
+	if clock == nil {
+		clock = time.Now
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	return clock(), maxAge
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	now, maxAge := s.settings()
+	w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := &Request{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: r.Header.Clone(),
+		Body:   body,
+		Time:   now,
+	}
+	s.check(rec, r, now, maxAge)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, rec)
+	mux, routes := s.mux, s.routes
+	s.mu.Unlock()
+
+	if !rec.Accepted() {
+		writeStatus(w, http.StatusForbidden, rec.Code, rec.Reason)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if routes == 0 {
+		writeStatus(w, http.StatusOK, 0, "")
+		return
+	}
+	if _, pattern := mux.Handler(r); pattern == "" {
+		writeStatus(w, http.StatusNotFound, http.StatusNotFound, "no handler for "+r.Method+" "+r.URL.Path)
+		return
+	}
+	mux.ServeHTTP(w, r)
+}
+
+// check fills in the Report, Code and Reason of rec.
+func (s *Server) check(rec *Request, r *http.Request, now time.Time, maxAge time.Duration) {
+	var missing []string
+	for _, name := range []string{"x-argus", "x-ladon", "x-gorgon", "x-khronos"} {
+		if r.Header.Get(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		rec.Code = CodeUnsigned
+		rec.Reason = "missing " + strings.Join(missing, ", ")
+		return
+	}
+
+	rep, err := ttsig.Verify(r)
+	if err != nil {
+		rec.Code = CodeInvalidSignature
+		rec.Reason = err.Error()
+		return
+	}
+	rec.Report = rep
+
+	if failed := rep.Failed(); len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, c := range failed {
+			names[i] = c.Name
+		}
+		rec.Code = CodeInvalidSignature
+		rec.Reason = "signature mismatch: " + strings.Join(names, ", ")
+		return
+	}
+
+	khronos, _ := strconv.ParseInt(r.Header.Get("x-khronos"), 10, 64)
+	age := now.Sub(time.Unix(khronos, 0))
+	if age > maxAge || age < -maxAge {
+		rec.Code = CodeExpired
+		rec.Reason = "request expired: x-khronos is " + age.Round(time.Second).String() + " from server time"
+	}
+}
+
+// writeStatus writes the {"status_code", "status_msg"} envelope the API
+// uses for errors.
+func writeStatus(w http.ResponseWriter, httpStatus, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg,omitempty"`
+	}{code, msg})
+}
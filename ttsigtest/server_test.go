@@ -0,0 +1,190 @@
+package ttsigtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skill/ttsig"
+)
+
+const testQuery = "device_id=7300000000000000001&aid=1233"
+
+var signedAt = time.UnixMilli(1700000000123)
+
+// signed returns a request to s signed at signedAt.
+func signed(t *testing.T, s *Server, method, path, body string) *http.Request {
+	t.Helper()
+	var b *ttsig.Body
+	if body != "" {
+		b = ttsig.NewBody([]byte(body))
+	}
+	req, err := ttsig.NewRequest(context.Background(), method, s.URL+path+"?"+testQuery, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ttsig.SignHTTPRequest(req, ttsig.SignConfig{Timestamp: signedAt}); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// send signs a request, lets change alter it and sends it to s.
+func send(t *testing.T, s *Server, method, path, body string, change func(*http.Request)) (int, map[string]any) {
+	t.Helper()
+	req := signed(t, s, method, path, body)
+	if change != nil {
+		change(req)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("response %q: %v", data, err)
+	}
+	return resp.StatusCode, out
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer()
+	t.Cleanup(s.Close)
+	s.SetClock(func() time.Time { return signedAt.Add(time.Minute) })
+	return s
+}
+
+func TestServerChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*http.Request)
+		status int
+		code   int
+		reason string
+	}{
+		{"signed", nil, http.StatusOK, 0, ""},
+		{"unsigned", func(r *http.Request) { r.Header.Del("x-argus"); r.Header.Del("x-ladon") }, http.StatusForbidden, CodeUnsigned, "missing x-argus, x-ladon"},
+		{"tampered query", func(r *http.Request) { r.URL.RawQuery += "&count=20" }, http.StatusForbidden, CodeInvalidSignature, "signature mismatch"},
+		{"tampered stub", func(r *http.Request) { r.Header.Set("x-ss-stub", strings.Repeat("0", 32)) }, http.StatusForbidden, CodeInvalidSignature, "x-ss-stub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			status, resp := send(t, s, http.MethodPost, "/aweme/v1/commit/follow/user/", "user_id=1&type=1", tt.change)
+			if status != tt.status || int(resp["status_code"].(float64)) != tt.code {
+				t.Errorf("status %d %v, want %d/%d", status, resp, tt.status, tt.code)
+			}
+
+			req := s.LastRequest()
+			if req == nil || len(s.Requests()) != 1 {
+				t.Fatalf("%d requests recorded", len(s.Requests()))
+			}
+			if req.Code != tt.code || !strings.Contains(req.Reason, tt.reason) {
+				t.Errorf("recorded %d %q, want %d %q", req.Code, req.Reason, tt.code, tt.reason)
+			}
+			if string(req.Body) != "user_id=1&type=1" {
+				t.Errorf("recorded body %q", req.Body)
+			}
+			if rejected := len(s.Rejected()) == 1; rejected != (tt.code != 0) {
+				t.Errorf("%d rejected", len(s.Rejected()))
+			}
+		})
+	}
+}
+
+func TestServerExpiry(t *testing.T) {
+	s := newTestServer(t)
+	s.SetClock(func() time.Time { return signedAt.Add(10 * time.Minute) })
+
+	status, resp := send(t, s, http.MethodGet, "/aweme/v1/feed/", "", nil)
+	if status != http.StatusForbidden || int(resp["status_code"].(float64)) != CodeExpired {
+		t.Fatalf("10 minutes late: status %d %v", status, resp)
+	}
+
+	s.SetMaxAge(time.Hour)
+	if status, resp := send(t, s, http.MethodGet, "/aweme/v1/feed/", "", nil); status != http.StatusOK {
+		t.Errorf("with a one-hour max age: status %d %v", status, resp)
+	}
+
+	s.SetMaxAge(0)
+	s.SetClock(func() time.Time { return signedAt.Add(-10 * time.Minute) })
+	if status, _ := send(t, s, http.MethodGet, "/aweme/v1/feed/", "", nil); status != http.StatusForbidden {
+		t.Errorf("10 minutes early: status %d", status)
+	}
+}
+
+func TestServerHandlers(t *testing.T) {
+	s := newTestServer(t)
+	s.RespondJSON("GET /aweme/v1/feed/", http.StatusOK, map[string]any{"status_code": 0, "has_more": 1})
+	s.HandleFunc("POST /aweme/v1/commit/follow/user/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]any{"status_code": 0, "echo": string(body)})
+	})
+
+	if _, resp := send(t, s, http.MethodGet, "/aweme/v1/feed/", "", nil); resp["has_more"] != 1.0 {
+		t.Errorf("feed: %v", resp)
+	}
+	if _, resp := send(t, s, http.MethodPost, "/aweme/v1/commit/follow/user/", "user_id=1", nil); resp["echo"] != "user_id=1" {
+		t.Errorf("handler did not get the body: %v", resp)
+	}
+	if status, _ := send(t, s, http.MethodGet, "/aweme/v1/other/", "", nil); status != http.StatusNotFound {
+		t.Errorf("unregistered path: status %d", status)
+	}
+
+	s.Reset()
+	if len(s.Requests()) != 0 || s.LastRequest() != nil {
+		t.Error("Reset kept requests")
+	}
+}
+
+// TestServerSettingsConcurrent changes the clock and max age while
+// requests are served; run with -race.
+func TestServerSettingsConcurrent(t *testing.T) {
+	s := newTestServer(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		reqs := make([]*http.Request, 5)
+		for j := range reqs {
+			reqs[j] = signed(t, s, http.MethodGet, "/aweme/v1/feed/", "")
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, req := range reqs {
+				resp, err := s.Client().Do(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		d := time.Duration(i) * time.Second
+		s.SetClock(func() time.Time { return signedAt.Add(d) })
+		s.SetMaxAge(time.Hour + d)
+	}
+	wg.Wait()
+
+	if n := len(s.Requests()); n != 20 {
+		t.Errorf("%d requests recorded, want 20", n)
+	}
+	if n := len(s.Rejected()); n != 0 {
+		t.Errorf("%d rejected: %s", n, s.Rejected()[0].Reason)
+	}
+}
@@ -0,0 +1,227 @@
+package ttsig
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Skill/ttsig/signer"
+)
+
+// Check is a single consistency rule evaluated by Verify.
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Report lists the outcome of every check Verify ran.
+type Report struct {
+	Checks []Check
+}
+
+// OK reports whether every check passed.
+func (r *Report) OK() bool {
+	for _, c := range r.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the checks that did not pass.
+func (r *Report) Failed() []Check {
+	var out []Check
+	for _, c := range r.Checks {
+		if !c.OK {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	for _, c := range r.Checks {
+		status := "ok  "
+		if !c.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%s %s", status, c.Name)
+		if c.Detail != "" {
+			fmt.Fprintf(&sb, ": %s", c.Detail)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (r *Report) add(name string, ok bool, format string, args ...any) {
+	c := Check{Name: name, OK: ok}
+	if !ok {
+		c.Detail = fmt.Sprintf(format, args...)
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+// Verify checks a fully signed request for internal consistency: that the
+// signature headers agree with each other and with the query, body and
+// cookies actually being sent. It does not need the signing config, so it
+// catches request builders that sign one thing and send another.
+//
+// The body is read and replaced, so req can still be sent afterwards. An
+// error is returned only when the request itself cannot be examined.
+func Verify(req *http.Request) (*Report, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("verify: reading body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	h := req.Header
+	query := req.URL.RawQuery
+	cookie := h.Get("Cookie")
+	rep := &Report{}
+
+	// Body digest and length.
+	digest, _ := NewBody(body).Digest()
+	stub := digest.Stub()
+	rep.add("x-ss-stub", h.Get("x-ss-stub") == stub,
+		"header %q, md5(body) %q", h.Get("x-ss-stub"), stub)
+
+	if len(body) > 0 {
+		length := h.Get("Content-Length")
+		if length == "" && req.ContentLength > 0 {
+			length = strconv.FormatInt(req.ContentLength, 10)
+		}
+		rep.add("content-length", length == strconv.Itoa(len(body)),
+			"header %q, body is %d bytes", length, len(body))
+	}
+
+	khronos, err := strconv.ParseInt(h.Get("x-khronos"), 10, 64)
+	if err != nil {
+		rep.add("x-khronos", false, "missing or invalid x-khronos %q", h.Get("x-khronos"))
+		return rep, nil
+	}
+
+	ticket, err := strconv.ParseInt(h.Get("x-ss-req-ticket"), 10, 64)
+	if err != nil {
+		rep.add("x-ss-req-ticket", false, "missing or invalid x-ss-req-ticket %q", h.Get("x-ss-req-ticket"))
+	} else {
+		delta := ticket - khronos*1000
+		rep.add("x-ss-req-ticket", delta > -1000 && delta < 1000,
+			"ticket %d is %dms away from khronos %d", ticket, delta, khronos)
+	}
+
+	verifyGorgon(rep, h.Get("x-gorgon"), khronos, query, body, cookie)
+	aid := verifyArgus(rep, h.Get("x-argus"), khronos, query, stub)
+	verifyLadon(rep, h.Get("x-ladon"), khronos, aid, query)
+
+	return rep, nil
+}
+
+func verifyGorgon(rep *Report, header string, khronos int64, query string, body []byte, cookie string) {
+	if header == "" {
+		rep.add("x-gorgon", false, "missing")
+		return
+	}
+	f, err := signer.DecodeGorgon(header)
+	if err != nil {
+		rep.add("x-gorgon", false, "%v", err)
+		return
+	}
+
+	rep.add("x-gorgon time", f.Unix == khronos, "gorgon %d, khronos %d", f.Unix, khronos)
+
+	// The query is always hashed, even when empty; the body and cookie
+	// fragments are zero when there is nothing to hash, as in the signer.
+	fragment := func(s string, always bool) [4]byte {
+		var out [4]byte
+		if s != "" || always {
+			sum := md5.Sum([]byte(s))
+			copy(out[:], sum[:4])
+		}
+		return out
+	}
+	want := fragment(query, true)
+	rep.add("x-gorgon query hash", f.QueryHash == want, "gorgon %x, md5(query) %x", f.QueryHash, want)
+	want = fragment(string(body), false)
+	rep.add("x-gorgon body hash", f.BodyHash == want, "gorgon %x, md5(body) %x", f.BodyHash, want)
+	want = fragment(cookie, false)
+	rep.add("x-gorgon cookie hash", f.CookieHash == want, "gorgon %x, md5(cookie) %x", f.CookieHash, want)
+}
+
+// verifyArgus checks the Argus bean and returns the aid it carries, or 0.
+func verifyArgus(rep *Report, header string, khronos int64, query, stub string) int64 {
+	if header == "" {
+		rep.add("x-argus", false, "missing")
+		return 0
+	}
+	pb, err := signer.Decrypt(header)
+	if err != nil {
+		rep.add("x-argus", false, "%v", err)
+		return 0
+	}
+
+	created, err := pb.GetInt(12)
+	if err != nil {
+		rep.add("x-argus time", false, "%v", err)
+	} else {
+		rep.add("x-argus time", int64(created>>1) == khronos, "argus %d, khronos %d", created>>1, khronos)
+	}
+
+	if got, err := pb.GetBytes(14); err != nil {
+		rep.add("x-argus query hash", false, "%v", err)
+	} else {
+		want := signer.GetQueryHash(query)
+		rep.add("x-argus query hash", bytes.Equal(got, want), "argus %x, sm3(query) %x", got, want)
+	}
+
+	if got, err := pb.GetBytes(13); err != nil {
+		rep.add("x-argus body hash", false, "%v", err)
+	} else {
+		want := signer.GetBodyHash(stub)
+		rep.add("x-argus body hash", bytes.Equal(got, want), "argus %x, sm3(stub) %x", got, want)
+	}
+
+	aid, _ := pb.GetUtf8(4)
+	n, _ := strconv.ParseInt(aid, 10, 64)
+	return n
+}
+
+// verifyLadon checks the Ladon timestamp. The key depends on the aid, taken
+// from the Argus bean or, failing that, the query; the details name the
+// source so a wrong key can be traced.
+func verifyLadon(rep *Report, header string, khronos, aid int64, query string) {
+	if header == "" {
+		rep.add("x-ladon", false, "missing")
+		return
+	}
+	source := "x-argus"
+	if aid == 0 {
+		aid, source = DefaultAppID, "default"
+		if q, err := ParseQuery(query); err == nil {
+			if n, err := q.AID(); err == nil && n != 0 {
+				aid, source = int64(n), "query"
+			}
+		}
+	}
+
+	f, err := signer.DecodeLadon(header, aid)
+	if err != nil {
+		rep.add("x-ladon", false, "%v; key aid %d from %s", err, aid, source)
+		return
+	}
+	rep.add("x-ladon time", f.Khronos == khronos, "ladon %d, khronos %d", f.Khronos, khronos)
+	rep.add("x-ladon aid", f.AID == aid, "ladon %d, %s %d", f.AID, source, aid)
+}
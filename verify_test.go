@@ -0,0 +1,161 @@
+package ttsig
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedRequest builds and signs a request the way a client would.
+func signedRequest(t *testing.T, query, body, cookie string) *http.Request {
+	t.Helper()
+	url := "https://api.example.com/aweme/v1/feed/"
+	if query != "" {
+		url += "?" + query
+	}
+	var b *Body
+	if body != "" {
+		b = NewBody([]byte(body))
+	}
+	req, err := NewRequest(context.Background(), http.MethodPost, url, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	headers, err := SignHTTPRequest(req, SignConfig{
+		DeviceID:  "7300000000000000001",
+		Timestamp: time.UnixMilli(1700000000123),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := headers["content-length"]; n != "" {
+		req.Header.Set("Content-Length", n)
+	}
+	return req
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	for _, query := range []string{"", "device_id=7300000000000000001&aid=1233&count=6", "device_id=7300000000000000001&keyword=100%&cursor=%zz"} {
+		for _, body := range []string{"", "user_id=1&type=1"} {
+			for _, cookie := range []string{"", "sessionid=5f1c3a"} {
+				name := "query=" + query + "/body=" + body + "/cookie=" + cookie
+				t.Run(name, func(t *testing.T) {
+					req := signedRequest(t, query, body, cookie)
+					rep, err := Verify(req)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !rep.OK() {
+						t.Errorf("valid request failed:\n%s", rep)
+					}
+
+					// The body must still be readable after Verify.
+					if req.Body != nil {
+						got, _ := io.ReadAll(req.Body)
+						if string(got) != body {
+							t.Errorf("body after Verify %q, want %q", got, body)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestVerifyDetectsChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*http.Request)
+		failed []string
+	}{
+		{
+			name: "body",
+			change: func(r *http.Request) {
+				r.Body = io.NopCloser(bytes.NewReader([]byte("user_id=2&type=1")))
+			},
+			failed: []string{"x-ss-stub", "x-gorgon body hash", "x-argus body hash"},
+		},
+		{
+			name:   "query",
+			change: func(r *http.Request) { r.URL.RawQuery += "&extra=1" },
+			failed: []string{"x-gorgon query hash", "x-argus query hash"},
+		},
+		{
+			name:   "cookie",
+			change: func(r *http.Request) { r.Header.Set("Cookie", "sessionid=other") },
+			failed: []string{"x-gorgon cookie hash"},
+		},
+		{
+			name:   "khronos",
+			change: func(r *http.Request) { r.Header.Set("x-khronos", "1700000005") },
+			failed: []string{"x-ss-req-ticket", "x-gorgon time", "x-argus time", "x-ladon time"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, "device_id=7300000000000000001&aid=1233", "user_id=1&type=1", "sessionid=5f1c3a")
+			tt.change(req)
+			rep, err := Verify(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed := map[string]bool{}
+			for _, c := range rep.Failed() {
+				failed[c.Name] = true
+			}
+			for _, name := range tt.failed {
+				if !failed[name] {
+					t.Errorf("%s passed after changing the %s", name, tt.name)
+				}
+			}
+			if len(failed) != len(tt.failed) {
+				t.Errorf("failed checks:\n%s", rep)
+			}
+		})
+	}
+}
+
+func TestVerifyLadonAIDSource(t *testing.T) {
+	const device = "device_id=7300000000000000001"
+	other := signedRequest(t, device+"&aid=1129", "", "")
+
+	tests := []struct {
+		name  string
+		query string
+		argus string
+		want  string
+	}{
+		{"x-argus", "", other.Header.Get("x-argus"), "key aid 1129 from x-argus"},
+		{"query", device + "&aid=1129", "", "key aid 1129 from query"},
+		{"default", device, "", "key aid 1233 from default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, device+"&aid=1128", "", "")
+			req.URL.RawQuery = tt.query
+			req.Header.Set("x-argus", tt.argus)
+
+			rep, err := Verify(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range rep.Failed() {
+				if c.Name == "x-ladon" {
+					if !strings.HasSuffix(c.Detail, tt.want) {
+						t.Errorf("detail %q, want suffix %q", c.Detail, tt.want)
+					}
+					return
+				}
+			}
+			t.Errorf("x-ladon did not fail:\n%s", rep)
+		})
+	}
+}